@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/armadi1809/cloximp/lox"
+)
+
+// Exit codes follow the sysexits convention used by Crafting Interpreters.
+const (
+	EXIT_OK            = 0
+	EXIT_USAGE         = 64
+	EXIT_COMPILE_ERROR = 65
+	EXIT_RUNTIME_ERROR = 70
+	EXIT_IO_ERROR      = 74
+)
+
+const usage = `Usage: jlox <command> [flags] [arguments]
+
+Commands:
+  run [flags] <file> [args...]   compile and run a script or compiled program
+  repl [flags]                   start an interactive session
+  disasm <file>                  print the bytecode for a script
+  check [-max-errors n] <file>   compile a script and report errors only
+  compile [-o out] <file>        write the compiled bytecode to a file
+
+Running jlox with a file and no command is the same as 'jlox run', and
+running it with no arguments starts the REPL.
+`
+
+func main() {
+	os.Exit(runCLI(os.Args[1:]))
+}
+
+func runCLI(args []string) int {
+	if len(args) == 0 {
+		return replCommand(nil)
+	}
+
+	switch args[0] {
+	case "run":
+		return runCommand(args[1:])
+	case "repl":
+		return replCommand(args[1:])
+	case "disasm":
+		return disasmCommand(args[1:])
+	case "check":
+		return checkCommand(args[1:])
+	case "compile":
+		return compileCommand(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+		return EXIT_OK
+	default:
+		return runCommand(args)
+	}
+}
+
+type debugFlags struct {
+	trace        *bool
+	dumpBytecode *bool
+	traceOut     *string
+}
+
+func addDebugFlags(fs *flag.FlagSet) *debugFlags {
+	return &debugFlags{
+		trace:        fs.Bool("trace", false, "trace the stack and each instruction as it executes"),
+		dumpBytecode: fs.Bool("dump-bytecode", false, "disassemble each function after compiling it"),
+		traceOut:     fs.String("trace-out", "", "write trace and bytecode output to `file` instead of stdout"),
+	}
+}
+
+// options builds the DebugOptions selected on the command line. The returned
+// function closes the trace output file, if one was opened.
+func (df *debugFlags) options() (lox.DebugOptions, func(), error) {
+	debug := lox.DebugOptions{PrintCode: *df.dumpBytecode, TraceExecution: *df.trace}
+	if *df.traceOut == "" {
+		return debug, func() {}, nil
+	}
+	f, err := os.Create(*df.traceOut)
+	if err != nil {
+		return debug, nil, err
+	}
+	debug.Out = f
+	return debug, func() { f.Close() }, nil
+}
+
+func addMaxErrorsFlag(fs *flag.FlagSet) *int {
+	return fs.Int("max-errors", lox.DEFAULT_MAX_ERRORS, "stop reporting compile errors after `n` (0 reports all)")
+}
+
+func newFlagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: jlox %s %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func parseFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return EXIT_OK, false
+		}
+		return EXIT_USAGE, false
+	}
+	return EXIT_OK, true
+}
+
+func runCommand(args []string) int {
+	fs := newFlagSet("run", "[flags] <file> [args...]")
+	df := addDebugFlags(fs)
+	maxErrors := addMaxErrorsFlag(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return EXIT_USAGE
+	}
+
+	debug, closeDebug, err := df.options()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not open trace output: %v\n", err)
+		return EXIT_IO_ERROR
+	}
+	defer closeDebug()
+
+	vm := lox.NewVM(
+		lox.WithDebug(debug),
+		lox.WithArgs(fs.Args()[1:]),
+		lox.WithMaxErrors(*maxErrors),
+	)
+	program, code := loadProgram(vm, fs.Arg(0))
+	if program == nil {
+		return code
+	}
+	if _, err := vm.Run(program); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCode(err)
+	}
+	return EXIT_OK
+}
+
+func replCommand(args []string) int {
+	fs := newFlagSet("repl", "[flags]")
+	df := addDebugFlags(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return EXIT_USAGE
+	}
+
+	debug, closeDebug, err := df.options()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not open trace output: %v\n", err)
+		return EXIT_IO_ERROR
+	}
+	defer closeDebug()
+
+	repl(os.Stdin, os.Stdout, os.Stderr, debug)
+	return EXIT_OK
+}
+
+func disasmCommand(args []string) int {
+	fs := newFlagSet("disasm", "<file>")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return EXIT_USAGE
+	}
+
+	program, code := loadProgram(lox.NewVM(), fs.Arg(0))
+	if program == nil {
+		return code
+	}
+	program.Disassemble(os.Stdout)
+	return EXIT_OK
+}
+
+func checkCommand(args []string) int {
+	fs := newFlagSet("check", "[-max-errors n] <file>")
+	maxErrors := addMaxErrorsFlag(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return EXIT_USAGE
+	}
+
+	program, code := loadProgram(lox.NewVM(lox.WithMaxErrors(*maxErrors)), fs.Arg(0))
+	if program == nil {
+		return code
+	}
+	return EXIT_OK
+}
+
+func compileCommand(args []string) int {
+	fs := newFlagSet("compile", "[-o out] <file>")
+	out := fs.String("o", "", "write bytecode to `file` (default: input with a .jloxc extension)")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return EXIT_USAGE
+	}
+
+	path := fs.Arg(0)
+	program, code := loadProgram(lox.NewVM(), path)
+	if program == nil {
+		return code
+	}
+
+	outPath := *out
+	if outPath == "" {
+		outPath = strings.TrimSuffix(path, filepath.Ext(path)) + ".jloxc"
+	}
+	f, err := os.Create(outPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not create output file: %v\n", err)
+		return EXIT_IO_ERROR
+	}
+	defer f.Close()
+	if err := lox.WriteBytecode(f, program); err != nil {
+		fmt.Fprintf(os.Stderr, "Could not write bytecode: %v\n", err)
+		return EXIT_IO_ERROR
+	}
+	return EXIT_OK
+}
+
+// loadProgram reads path and compiles it, or decodes it if it was written by
+// 'jlox compile'. On failure it returns nil and the exit code to use.
+func loadProgram(vm *lox.VM, path string) (*lox.Program, int) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read file: %v\n", err)
+		return nil, EXIT_IO_ERROR
+	}
+
+	if lox.IsBytecode(source) {
+		program, err := lox.ReadBytecode(source)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not load %s: %v\n", path, err)
+			return nil, EXIT_IO_ERROR
+		}
+		return program, EXIT_OK
+	}
+
+	program, err := vm.CompileNamed(path, string(source))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil, exitCode(err)
+	}
+	return program, EXIT_OK
+}
+
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return EXIT_OK
+	case errors.Is(err, lox.ErrCompile):
+		return EXIT_COMPILE_ERROR
+	case errors.Is(err, lox.ErrRuntime):
+		return EXIT_RUNTIME_ERROR
+	}
+	return EXIT_IO_ERROR
+}
+
+// repl reads lines from in until it ends, running each complete input in
+// one VM so definitions carry over. Prompts and program output go to out,
+// and errors to errOut.
+func repl(in io.Reader, out, errOut io.Writer, debug lox.DebugOptions) {
+	scanner := bufio.NewScanner(in)
+	vm := lox.NewVM(
+		lox.WithReplMode(true),
+		lox.WithDebug(debug),
+		lox.WithOutput(out),
+		lox.WithErrorOutput(errOut),
+	)
+	var input strings.Builder
+	for {
+		if input.Len() == 0 {
+			fmt.Fprint(out, "> ")
+		} else {
+			fmt.Fprint(out, "... ")
+		}
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			break
+		}
+		line := scanner.Text()
+		if input.Len() == 0 && len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		input.WriteString(line)
+		input.WriteString("\n")
+		if !lox.InputComplete(input.String()) {
+			continue
+		}
+		vm.Interpret(input.String())
+		input.Reset()
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runJlox runs the CLI with args and returns its exit code and what it
+// wrote to stdout and stderr.
+func runJlox(t *testing.T, args ...string) (code int, stdout, stderr string) {
+	t.Helper()
+	dir := t.TempDir()
+	outFile, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer outFile.Close()
+	errFile, err := os.Create(filepath.Join(dir, "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer errFile.Close()
+
+	savedOut, savedErr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = outFile, errFile
+	code = runCLI(args)
+	os.Stdout, os.Stderr = savedOut, savedErr
+
+	out, err := os.ReadFile(outFile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	errOut, err := os.ReadFile(errFile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code, string(out), string(errOut)
+}
+
+// writeScript writes source to a file in a temporary directory and returns
+// its path.
+func writeScript(t *testing.T, source string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "script.jlox")
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTraceFlags(t *testing.T) {
+	script := writeScript(t, "print 1 + 2;")
+
+	code, stdout, _ := runJlox(t, "run", script)
+	if code != EXIT_OK || stdout != "3\n" {
+		t.Errorf("run = %d, %q; want %d, %q", code, stdout, EXIT_OK, "3\n")
+	}
+
+	code, stdout, _ = runJlox(t, "run", "-trace", "-dump-bytecode", script)
+	if code != EXIT_OK {
+		t.Fatalf("run -trace = %d", code)
+	}
+	for _, want := range []string{"== <script> ==", "[ <script> ][ 3 ]", "OP_ADD", "3\n"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("run -trace output:\n%s\nwant it to contain %q", stdout, want)
+		}
+	}
+
+	traceFile := filepath.Join(t.TempDir(), "trace.txt")
+	code, stdout, _ = runJlox(t, "run", "-trace", "-trace-out", traceFile, script)
+	if code != EXIT_OK || stdout != "3\n" {
+		t.Errorf("run -trace-out = %d, %q; want %d, %q", code, stdout, EXIT_OK, "3\n")
+	}
+	trace, err := os.ReadFile(traceFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(trace), "[ <script> ][ 3 ]") {
+		t.Errorf("trace file:\n%s\nwant the execution trace", trace)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	ok := writeScript(t, `print "ok";`)
+	compileError := writeScript(t, "print ;")
+	runtimeError := writeScript(t, "print nil + 1;")
+	missing := filepath.Join(t.TempDir(), "missing.jlox")
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"run", []string{"run", ok}, EXIT_OK},
+		{"run without command", []string{ok}, EXIT_OK},
+		{"run compile error", []string{"run", compileError}, EXIT_COMPILE_ERROR},
+		{"run runtime error", []string{"run", runtimeError}, EXIT_RUNTIME_ERROR},
+		{"run missing file", []string{"run", missing}, EXIT_IO_ERROR},
+		{"run no file", []string{"run"}, EXIT_USAGE},
+		{"run bad flag", []string{"run", "-nope", ok}, EXIT_USAGE},
+		{"run unwritable trace", []string{"run", "-trace-out", filepath.Join(missing, "trace"), ok}, EXIT_IO_ERROR},
+		{"check", []string{"check", ok}, EXIT_OK},
+		{"check compile error", []string{"check", compileError}, EXIT_COMPILE_ERROR},
+		{"check runtime error", []string{"check", runtimeError}, EXIT_OK},
+		{"check two files", []string{"check", ok, ok}, EXIT_USAGE},
+		{"disasm", []string{"disasm", ok}, EXIT_OK},
+		{"disasm compile error", []string{"disasm", compileError}, EXIT_COMPILE_ERROR},
+		{"disasm missing file", []string{"disasm", missing}, EXIT_IO_ERROR},
+		{"compile compile error", []string{"compile", compileError}, EXIT_COMPILE_ERROR},
+		{"compile unwritable output", []string{"compile", "-o", filepath.Join(missing, "out.jloxc"), ok}, EXIT_IO_ERROR},
+		{"repl with argument", []string{"repl", ok}, EXIT_USAGE},
+		{"help", []string{"help"}, EXIT_OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, stderr := runJlox(t, tt.args...)
+			if code != tt.want {
+				t.Errorf("exit code = %d, want %d; stderr:\n%s", code, tt.want, stderr)
+			}
+		})
+	}
+}
+
+func TestRunPassesArguments(t *testing.T) {
+	script := writeScript(t, "for (var i = 0; i < argc(); i = i + 1) print argv(i);")
+	code, stdout, stderr := runJlox(t, "run", script, "a", "-b")
+	if code != EXIT_OK {
+		t.Fatalf("exit code = %d; stderr:\n%s", code, stderr)
+	}
+	if want := "a\n-b\n"; stdout != want {
+		t.Errorf("output = %q, want %q", stdout, want)
+	}
+}
+
+func TestCompileThenRun(t *testing.T) {
+	script := writeScript(t, `fun greet(name) { return "hi " + name; } print greet("bo");`)
+	out := filepath.Join(t.TempDir(), "greet.jloxc")
+	if code, _, stderr := runJlox(t, "compile", "-o", out, script); code != EXIT_OK {
+		t.Fatalf("compile exit code = %d; stderr:\n%s", code, stderr)
+	}
+	code, stdout, stderr := runJlox(t, "run", out)
+	if code != EXIT_OK {
+		t.Fatalf("run exit code = %d; stderr:\n%s", code, stderr)
+	}
+	if stdout != "hi bo\n" {
+		t.Errorf("output = %q, want %q", stdout, "hi bo\n")
+	}
+}
+
+func TestErrorsGoToStderr(t *testing.T) {
+	script := writeScript(t, "print 1;\nprint nil + 1;")
+	code, stdout, stderr := runJlox(t, "run", script)
+	if code != EXIT_RUNTIME_ERROR {
+		t.Errorf("exit code = %d, want %d", code, EXIT_RUNTIME_ERROR)
+	}
+	if stdout != "1\n" {
+		t.Errorf("stdout = %q, want %q", stdout, "1\n")
+	}
+	if !strings.HasPrefix(stderr, "error: ") || !strings.Contains(stderr, "[line 2] in script") {
+		t.Errorf("stderr = %q, want the runtime error and its trace", stderr)
+	}
+}
+
+func TestMaxErrorsFlag(t *testing.T) {
+	script := writeScript(t, "var = 1;\nvar = 2;\nvar = 3;")
+	for _, command := range []string{"run", "check"} {
+		code, _, stderr := runJlox(t, command, "-max-errors", "2", script)
+		if code != EXIT_COMPILE_ERROR {
+			t.Errorf("%s exit code = %d, want %d", command, code, EXIT_COMPILE_ERROR)
+		}
+		if n := strings.Count(stderr, "Expected variable name"); n != 2 {
+			t.Errorf("%s reported %d errors, want 2:\n%s", command, n, stderr)
+		}
+		if !strings.Contains(stderr, "error: Too many errors, stopping.") {
+			t.Errorf("%s stderr:\n%s\nwant the stop message", command, stderr)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/armadi1809/cloximp/lox"
+)
+
+func TestRepl(t *testing.T) {
+	input := strings.Join([]string{
+		"var x = 1;",
+		"x + 1",
+		"fun f() {",
+		"  return x * 10;",
+		"}",
+		"f()",
+		"",
+		"y",
+		"x = 5;",
+		"f()",
+	}, "\n")
+	var out, errOut strings.Builder
+	repl(strings.NewReader(input), &out, &errOut, lox.DebugOptions{})
+
+	// A declaration prints nothing, an expression statement echoes its
+	// value, and the lines of an unbalanced input get continuation prompts.
+	want := "> > 2\n> ... ... > 10\n> > > 5\n> 50\n> \n"
+	if got := out.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+	if got := errOut.String(); !strings.HasPrefix(got, "error: Undefined variable 'y'.") {
+		t.Errorf("error output = %q, want the undefined variable error", got)
+	}
+}
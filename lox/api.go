@@ -0,0 +1,139 @@
+package lox
+
+import (
+	"fmt"
+	"io"
+)
+
+// Option configures a VM created by NewVM.
+type Option func(*VM)
+
+// WithOutput sends the output of print statements to w.
+func WithOutput(w io.Writer) Option {
+	return func(vm *VM) {
+		vm.stdout = w
+	}
+}
+
+// WithErrorOutput sends the diagnostics printed by Interpret to w.
+func WithErrorOutput(w io.Writer) Option {
+	return func(vm *VM) {
+		vm.stderr = w
+	}
+}
+
+// WithDebug turns on the bytecode dump and execution trace selected by debug.
+func WithDebug(debug DebugOptions) Option {
+	return func(vm *VM) {
+		vm.debug = debug
+	}
+}
+
+// WithMaxErrors limits how many diagnostics a single compile collects. A
+// limit of zero or less collects them all.
+func WithMaxErrors(limit int) Option {
+	return func(vm *VM) {
+		vm.maxErrors = limit
+	}
+}
+
+// WithArgs sets the values scripts see through argc() and argv().
+func WithArgs(args []string) Option {
+	return func(vm *VM) {
+		vm.scriptArgs = args
+	}
+}
+
+// WithReplMode makes top-level expression statements print their value, the
+// way an interactive session expects.
+func WithReplMode(enabled bool) Option {
+	return func(vm *VM) {
+		vm.replMode = enabled
+	}
+}
+
+// Program is compiled Lox code. It can be run any number of times, on any VM.
+type Program struct {
+	function *ObjFunction
+}
+
+// Disassemble writes the bytecode of the program and every function nested
+// in it to w.
+func (p *Program) Disassemble(w io.Writer) {
+	DisassembleFunction(w, p.function)
+}
+
+// Compile compiles source without running it. If it fails, the error is a
+// *CompileError.
+func (vm *VM) Compile(source string) (*Program, error) {
+	return vm.CompileNamed("<script>", source)
+}
+
+// CompileNamed is like Compile, but uses name, typically a file path, to
+// identify the source in diagnostics.
+func (vm *VM) CompileNamed(name, source string) (*Program, error) {
+	function, err := vm.compile(&SourceFile{Name: name, Text: source})
+	if err != nil {
+		return nil, err
+	}
+	return &Program{function: function}, nil
+}
+
+// Run executes program against the VM's globals. If the program ends with an
+// expression statement, its value is returned; otherwise the result is nil.
+// If it fails, the error is a *RuntimeError.
+func (vm *VM) Run(program *Program) (Value, error) {
+	if vm.runFunction(program.function) != INTERPRET_OK {
+		return nil, vm.lastError
+	}
+	return vm.result, nil
+}
+
+// Get returns the value of the global variable name.
+func (vm *VM) Get(name string) (Value, bool) {
+	val, ok := vm.globals[CreateStringObj(name)]
+	if !ok {
+		val, ok = vm.builtins[CreateStringObj(name)]
+	}
+	return val, ok
+}
+
+// Set defines or overwrites the global variable name. It fails if name is
+// a constant declared with let or const.
+func (vm *VM) Set(name string, val Value) error {
+	key := CreateStringObj(name)
+	if _, ok := vm.main.constants[key]; ok {
+		return fmt.Errorf("Can't assign to constant '%s'.", name)
+	}
+	vm.globals[key] = val
+	return nil
+}
+
+// NewString wraps s as a Lox string value.
+func NewString(s string) Value {
+	return ObjVal{Object: CreateStringObj(s)}
+}
+
+// InputComplete reports whether source has balanced braces and parentheses
+// and no unterminated string, so a REPL knows when to stop reading
+// continuation lines.
+func InputComplete(source string) bool {
+	sc := &Scanner{}
+	sc.initScanner(source)
+	depth := 0
+	for {
+		tok := sc.scanToken()
+		switch tok.Type {
+		case TOKEN_EOF:
+			return depth <= 0
+		case TOKEN_ERROR:
+			if sc.isAtEnd() {
+				return false
+			}
+		case TOKEN_LEFT_BRACE, TOKEN_LEFT_PAREN:
+			depth++
+		case TOKEN_RIGHT_BRACE, TOKEN_RIGHT_PAREN:
+			depth--
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package lox_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/armadi1809/cloximp/lox"
+)
+
+func TestCompileAndRun(t *testing.T) {
+	var out strings.Builder
+	vm := lox.NewVM(lox.WithOutput(&out))
+	program, err := vm.Compile("var total = 0;\nfun add(n) { total = total + n; return total; }\nprint add(2);\nadd(3);")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Compile ran the program: %q", out.String())
+	}
+
+	result, err := vm.Run(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "2\n" {
+		t.Errorf("output = %q, want %q", out.String(), "2\n")
+	}
+	if result != lox.IntVal(5) {
+		t.Errorf("result = %v, want 5", result)
+	}
+
+	// A program can be run again; this one redeclares its globals.
+	result, err = vm.Run(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != lox.IntVal(5) {
+		t.Errorf("second result = %v, want 5", result)
+	}
+}
+
+func TestRunResultIsNilWithoutExpression(t *testing.T) {
+	vm := lox.NewVM(lox.WithOutput(&strings.Builder{}))
+	program, err := vm.Compile("var x = 1;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := vm.Run(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (lox.NilVal{}) {
+		t.Errorf("result = %v, want nil", result)
+	}
+}
+
+func TestProgramRunsOnAnyVM(t *testing.T) {
+	program, err := lox.NewVM().Compile("greeting + \", \" + name;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Ada", "Bo"} {
+		vm := lox.NewVM()
+		vm.Set("greeting", lox.NewString("Hello"))
+		vm.Set("name", lox.NewString(name))
+		result, err := vm.Run(program)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "Hello, " + name; result.String() != want {
+			t.Errorf("result = %q, want %q", result.String(), want)
+		}
+	}
+}
+
+func TestSetAndGet(t *testing.T) {
+	vm := lox.NewVM()
+	vm.Set("limit", lox.IntVal(10))
+	program, err := vm.Compile("var doubled = limit * 2; limit = 11;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vm.Run(program); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want lox.Value
+	}{
+		{"limit", lox.IntVal(11)},
+		{"doubled", lox.IntVal(20)},
+	}
+	for _, tt := range tests {
+		got, ok := vm.Get(tt.name)
+		if !ok || got != tt.want {
+			t.Errorf("Get(%q) = %v, %v; want %v, true", tt.name, got, ok, tt.want)
+		}
+	}
+	if _, ok := vm.Get("clock"); !ok {
+		t.Error("Get did not find the clock native")
+	}
+	if got, ok := vm.Get("missing"); ok {
+		t.Errorf("Get(missing) = %v, true; want not found", got)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	var out, errOut strings.Builder
+	vm := lox.NewVM(
+		lox.WithOutput(&out),
+		lox.WithErrorOutput(&errOut),
+		lox.WithArgs([]string{"x", "y"}),
+	)
+	if result := vm.Interpret("print argc(); print argv(1);"); result != lox.INTERPRET_OK {
+		t.Fatalf("Interpret = %v: %s", result, errOut.String())
+	}
+	if out.String() != "2\ny\n" {
+		t.Errorf("output = %q, want %q", out.String(), "2\ny\n")
+	}
+	if result := vm.Interpret("print missing;"); result != lox.INTERPRET_RUNTIME_ERROR {
+		t.Errorf("Interpret = %v, want a runtime error", result)
+	}
+	if !strings.Contains(errOut.String(), "Undefined variable 'missing'.") {
+		t.Errorf("error output = %q, want the undefined variable error", errOut.String())
+	}
+}
+
+func TestRunDeclaresConstantsAgain(t *testing.T) {
+	vm := lox.NewVM()
+	program, err := vm.Compile("const limit = 3; let name = \"x\"; limit * 2;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for run := 1; run <= 2; run++ {
+		result, err := vm.Run(program)
+		if err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+		if result != lox.IntVal(6) {
+			t.Errorf("run %d result = %v, want 6", run, result)
+		}
+	}
+
+	// A different declaration of the same name is still refused.
+	other, err := vm.Compile("const limit = 4;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vm.Run(other); err == nil || !strings.Contains(err.Error(), "Can't redefine constant 'limit'.") {
+		t.Errorf("redefining from another program: got %v, want the redefine error", err)
+	}
+}
+
+func TestSetRefusesConstants(t *testing.T) {
+	vm := lox.NewVM()
+	program, err := vm.Compile("let x = 1;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vm.Run(program); err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.Set("x", lox.IntVal(5)); err == nil || err.Error() != "Can't assign to constant 'x'." {
+		t.Errorf("Set(x) = %v, want the constant error", err)
+	}
+	if got, _ := vm.Get("x"); got != lox.IntVal(1) {
+		t.Errorf("Get(x) = %v after refused Set, want 1", got)
+	}
+	if err := vm.Set("y", lox.IntVal(5)); err != nil {
+		t.Errorf("Set(y) = %v, want nil", err)
+	}
+}
@@ -0,0 +1,86 @@
+package lox
+
+const (
+	OP_RETURN byte = iota
+	OP_CONSTANT
+	OP_NEGATE
+	OP_ADD
+	OP_SUBSTRACT
+	OP_MULTIPLY
+	OP_DIVIDE
+	OP_NIL
+	OP_TRUE
+	OP_FALSE
+	OP_POP
+	OP_DEFINE_GLOBAL
+	OP_GET_GLOBAL
+	OP_SET_GLOBAL
+	OP_GET_LOCAL
+	OP_SET_LOCAL
+	OP_NOT
+	OP_EQUAL
+	OP_GREATER
+	OP_LESS
+	OP_PRINT
+	OP_JUMP_IF_FALSE
+	OP_JUMP
+	OP_LOOP
+	OP_CALL
+	OP_CLOSURE
+	OP_GET_UPVALUE
+	OP_SET_UPVALUE
+	OP_CLOSE_UPVALUE
+	OP_CLASS
+	OP_GET_PROPERTY
+	OP_SET_PROPERTY
+	OP_METHOD
+	OP_INHERIT
+	OP_GET_SUPER
+	OP_BUILD_LIST
+	OP_INDEX_GET
+	OP_INDEX_SET
+	OP_BUILD_MAP
+	OP_TO_STRING
+	OP_POPN
+	OP_DEFINE_CONST
+	OP_TRY
+	OP_END_TRY
+	OP_THROW
+	OP_SAVE_THROWN
+	OP_IMPORT
+	OP_IMPORT_FROM
+	OP_EXPORT
+	OP_CALL_NAMED
+	OP_SKIP_DEFAULT
+	OP_FLOOR_DIVIDE
+	OP_MODULO
+	OP_POWER
+	OP_BIT_AND
+	OP_BIT_OR
+	OP_BIT_XOR
+	OP_BIT_NOT
+	OP_SHIFT_LEFT
+	OP_SHIFT_RIGHT
+	OP_DUP
+)
+
+type Chunk struct {
+	Code      []byte
+	Constants ValueArray
+	spans     []Span
+}
+
+// Write appends b, remembering the span of the source token it came from.
+func (c *Chunk) Write(b byte, span Span) {
+	c.Code = append(c.Code, b)
+	c.spans = append(c.spans, span)
+}
+
+func (c *Chunk) Count() int {
+	return len(c.Code)
+}
+
+func (c *Chunk) AddConstant(val Value) int {
+	c.Constants.values = append(c.Constants.values, val)
+	return len(c.Constants.values) - 1
+}
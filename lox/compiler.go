@@ -0,0 +1,1961 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+type Precedence int
+type FunctionType int
+type ParseFn func(canAssign bool)
+
+const (
+	PREC_NONE       = iota
+	PREC_ASSIGNMENT // =
+	PREC_OR         // or
+	PREC_AND        // and
+	PREC_EQUALITY   // == !=
+	PREC_COMPARISON // < > <= >=
+	PREC_BIT_OR     // |
+	PREC_BIT_XOR    // ^
+	PREC_BIT_AND    // &
+	PREC_SHIFT      // << >>
+	PREC_TERM       // + -
+	PREC_FACTOR     // * / ~/ %
+	PREC_UNARY      // ! - ~
+	PREC_POWER      // **
+	PREC_CALL       // . ()
+	PREC_PRIMARY
+)
+
+// Completion kinds record how a try statement was left, so its finally clause
+// knows what to do once it has run. Each distinct break or continue target is
+// numbered from COMPLETION_JUMP.
+const (
+	COMPLETION_NORMAL = iota
+	COMPLETION_THROW
+	COMPLETION_RETURN
+	COMPLETION_JUMP
+)
+
+const (
+	TYPE_FUNCTION = iota
+	TYPE_INITIALIZER
+	TYPE_METHOD
+	TYPE_SCRIPT
+)
+
+// ANONYMOUS_FUNCTION names functions created by function expressions in
+// printed values and stack traces.
+const ANONYMOUS_FUNCTION = "anonymous"
+
+type Parser struct {
+	current     Token
+	previous    Token
+	hadError    bool
+	panicMode   bool
+	diagnostics []Diagnostic
+	// maxErrors caps how many diagnostics are collected; zero or less
+	// means no limit. truncated records that later errors were dropped.
+	maxErrors int
+	truncated bool
+}
+
+// signature is set for locals declared with `fun`, so calls to them can be
+// checked at compile time.
+type Local struct {
+	name       Token
+	depth      int
+	isCaptured bool
+	isConst    bool
+	signature  *signature
+}
+
+// signature records the function a `fun` declaration binds. Calls through
+// the binding are checked once the whole script has been compiled, and only
+// if nothing assigned to the binding, since a later assignment can replace
+// the function before an earlier call runs.
+type signature struct {
+	function *ObjFunction
+	assigned bool
+}
+
+// pendingCall is a call whose arguments are checked against its callee's
+// signature at the end of compilation.
+type pendingCall struct {
+	callee   *signature
+	paren    Token
+	argCount int
+	names    []Token
+}
+
+type Upvalue struct {
+	index   byte
+	isLocal bool
+	isConst bool
+}
+
+// Loop tracks an enclosing loop so break and continue know where to jump
+// and which locals to discard on the way out.
+type Loop struct {
+	Enclosing  *Loop
+	Label      Token
+	Start      int
+	ScopeDepth int
+	BreakJumps []int
+	Try        *TryContext
+}
+
+// TryContext tracks an enclosing try statement. Two hidden locals starting
+// at Slot hold the pending value and completion kind; every way out of the
+// try and catch blocks jumps to the finally clause through FinallyJumps.
+type TryContext struct {
+	Enclosing    *TryContext
+	ScopeDepth   int
+	Slot         int
+	FinallyJumps []int
+	HasReturn    bool
+	Exits        []TryExit
+}
+
+// TryExit is a break or continue that left a try statement.
+type TryExit struct {
+	Loop    *Loop
+	IsBreak bool
+}
+
+type ClassCompiler struct {
+	Enclosing     *ClassCompiler
+	HasSuperclass bool
+}
+
+type Compiler struct {
+	Sc           *Scanner
+	Function     *ObjFunction
+	Type         FunctionType
+	Ps           *Parser
+	rules        map[TokenType]ParseRule
+	Locals       []Local
+	LocalCount   int
+	ScopeDepth   int
+	Upvalues     []Upvalue
+	Enclosing    *Compiler
+	CurrentClass *ClassCompiler
+	CurrentLoop  *Loop
+	CurrentTry   *TryContext
+	// GlobalConstants maps global constants declared with a literal
+	// initializer to their value so later reads can be inlined. Only the
+	// script compiler fills it in.
+	GlobalConstants map[string]Value
+	// GlobalSignatures maps global functions declared with `fun` to their
+	// compiled function so calls can be checked at compile time.
+	GlobalSignatures map[string]*signature
+	// callee is the signature of the function named just before a '(',
+	// if known, for the call rule to check.
+	callee *signature
+	// pendingCalls are the calls to check once the script is compiled.
+	// Only the script compiler fills it in.
+	pendingCalls []pendingCall
+
+	ReplMode bool
+	Debug    DebugOptions
+	File     *SourceFile
+
+	// resultOnStack is set when the script ends in an expression statement
+	// whose value was left on the stack to become the program's result.
+	resultOnStack bool
+}
+
+type ParseRule struct {
+	prefix     ParseFn
+	infix      ParseFn
+	precedence Precedence
+}
+
+func (c *Compiler) initCompiler(funct FunctionType) {
+	local := Local{
+		depth: 0,
+		name: Token{
+			Lexeme: "",
+		},
+	}
+	if funct == TYPE_METHOD || funct == TYPE_INITIALIZER {
+		local.name.Lexeme = "this"
+	}
+
+	c.Sc = &Scanner{}
+	c.Function = NewFunction()
+	c.Type = funct
+	c.Ps = &Parser{
+		panicMode: false,
+		hadError:  false,
+	}
+	c.Locals = []Local{local}
+	c.ScopeDepth = 0
+	c.LocalCount = 1
+
+}
+
+func (c *Compiler) compile(file *SourceFile) (*ObjFunction, error) {
+	c.File = file
+	c.Function.source = file
+	c.Sc.initScanner(file.Text)
+	c.initRules()
+	c.advance()
+	for !c.match(TOKEN_EOF) {
+		c.declaration()
+	}
+	c.checkPendingCalls()
+	function := c.endCompiler()
+
+	if c.Ps.hadError {
+		return nil, &CompileError{
+			Diagnostics: c.Ps.diagnostics,
+			Truncated:   c.Ps.truncated,
+		}
+	}
+
+	return function, nil
+}
+
+func (c *Compiler) declaration() {
+	if c.match(TOKEN_CLASS) {
+		c.classDeclaration()
+	} else if c.check(TOKEN_FUN) && c.Sc.peekToken().Type != TOKEN_LEFT_PAREN {
+		c.advance()
+		c.functionDeclaration()
+	} else if c.match(TOKEN_VAR) {
+		c.varDeclaration()
+	} else if c.match(TOKEN_LET) || c.match(TOKEN_CONST) {
+		c.constDeclaration()
+	} else if c.match(TOKEN_IMPORT) {
+		c.importDeclaration()
+	} else if c.match(TOKEN_EXPORT) {
+		c.exportDeclaration()
+	} else {
+		c.statement()
+	}
+
+	if c.Ps.panicMode {
+		c.synchronize()
+	}
+}
+
+func (c *Compiler) classDeclaration() {
+	c.consume(TOKEN_IDENTIFIER, "Expect class name.")
+	className := c.Ps.previous
+	nameConstant := c.identifierConstant(c.Ps.previous)
+	c.declareVariable()
+	c.forgetGlobalSignature(className.Lexeme)
+
+	c.emitBytes(OP_CLASS, nameConstant)
+	c.defineVariable(nameConstant)
+
+	classCompiler := &ClassCompiler{Enclosing: c.CurrentClass}
+	c.CurrentClass = classCompiler
+
+	if c.match(TOKEN_LESS) {
+		c.consume(TOKEN_IDENTIFIER, "Expect superclass name.")
+		c.variable(false)
+		if identifiersEqual(className, c.Ps.previous) {
+			c.error("A class can't inherit from itself.")
+		}
+
+		c.beginBlock()
+		c.addLocal(syntheticToken("super"))
+		c.defineVariable(0)
+
+		c.namedVariable(className, false)
+		c.emitByte(OP_INHERIT)
+		classCompiler.HasSuperclass = true
+	}
+
+	c.namedVariable(className, false)
+	c.consume(TOKEN_LEFT_BRACE, "Expect '{' before class body.")
+	for !c.check(TOKEN_RIGHT_BRACE) && !c.check(TOKEN_EOF) {
+		c.method()
+	}
+	c.consume(TOKEN_RIGHT_BRACE, "Expect '}' after class body.")
+	c.emitByte(OP_POP)
+
+	if classCompiler.HasSuperclass {
+		c.endBlock()
+	}
+	c.CurrentClass = classCompiler.Enclosing
+}
+
+func (c *Compiler) method() {
+	c.consume(TOKEN_IDENTIFIER, "Expect method name.")
+	constant := c.identifierConstant(c.Ps.previous)
+	var funcType FunctionType = TYPE_METHOD
+	if c.Ps.previous.Lexeme == "init" {
+		funcType = TYPE_INITIALIZER
+	}
+	c.function(funcType)
+	c.emitBytes(OP_METHOD, constant)
+}
+
+func (c *Compiler) functionDeclaration() {
+	global := c.parseVariable("Expect function name.")
+	name := c.Ps.previous
+	c.markInitialized()
+	function := c.function(TYPE_FUNCTION)
+	if c.ScopeDepth > 0 {
+		c.Locals[c.LocalCount-1].signature = &signature{function: function}
+	} else if c.Type == TYPE_SCRIPT {
+		if c.GlobalSignatures == nil {
+			c.GlobalSignatures = make(map[string]*signature)
+		}
+		c.GlobalSignatures[name.Lexeme] = &signature{function: function}
+	}
+	c.defineVariable(global)
+}
+
+func (c *Compiler) varDeclaration() {
+	global := c.parseVariable("Expected variable name")
+	if c.match(TOKEN_EQUAL) {
+		c.expression()
+	} else {
+		c.emitByte(OP_NIL)
+	}
+	c.consume(TOKEN_SEMICOLON,
+		"Expect ';' after variable declaration.")
+	c.defineVariable(global)
+}
+
+// constDeclaration compiles an immutable binding. Assigning to a local
+// constant is a compile error; assigning to a global one fails at runtime.
+func (c *Compiler) constDeclaration() {
+	global := c.parseVariable("Expect constant name.")
+	name := c.Ps.previous
+	if c.ScopeDepth > 0 {
+		c.Locals[c.LocalCount-1].isConst = true
+	}
+	if !c.match(TOKEN_EQUAL) {
+		c.errorAt(name, fmt.Sprintf("Constant '%s' must be initialized.", name.Lexeme))
+	}
+	initializer := c.Ps.current
+	c.expression()
+	singleToken := c.Ps.previous.Offset == initializer.Offset
+	c.consume(TOKEN_SEMICOLON, "Expect ';' after constant declaration.")
+	if c.ScopeDepth > 0 {
+		c.markInitialized()
+		return
+	}
+	if singleToken {
+		if val, ok := literalValue(initializer); ok && c.Type == TYPE_SCRIPT {
+			if c.GlobalConstants == nil {
+				c.GlobalConstants = make(map[string]Value)
+			}
+			c.GlobalConstants[name.Lexeme] = val
+		}
+	}
+	c.emitByteAt(OP_DEFINE_CONST, name)
+	c.emitByteAt(global, name)
+}
+
+// importDeclaration compiles `import "path" as name;`, `import "path";` and
+// `import { a, b as c } from "path";`. Imported names become globals of the
+// importing module.
+func (c *Compiler) importDeclaration() {
+	if c.Type != TYPE_SCRIPT || c.ScopeDepth > 0 {
+		c.error("Can only import from top-level code.")
+	}
+	if !c.match(TOKEN_LEFT_BRACE) {
+		c.emitImport(c.modulePath())
+		if c.matchContextual("as") {
+			c.consume(TOKEN_IDENTIFIER, "Expect module name after 'as'.")
+			c.forgetGlobalSignature(c.Ps.previous.Lexeme)
+			c.emitBytes(OP_DEFINE_GLOBAL, c.identifierConstant(c.Ps.previous))
+		} else {
+			c.emitByte(OP_POP)
+		}
+		c.consume(TOKEN_SEMICOLON, "Expect ';' after import.")
+		return
+	}
+
+	var names, aliases []Token
+	for !c.check(TOKEN_RIGHT_BRACE) {
+		c.consume(TOKEN_IDENTIFIER, "Expect imported name.")
+		name := c.Ps.previous
+		alias := name
+		if c.matchContextual("as") {
+			c.consume(TOKEN_IDENTIFIER, "Expect name after 'as'.")
+			alias = c.Ps.previous
+		}
+		names = append(names, name)
+		aliases = append(aliases, alias)
+		if !c.match(TOKEN_COMMA) {
+			break
+		}
+	}
+	c.consume(TOKEN_RIGHT_BRACE, "Expect '}' after imported names.")
+	if !c.matchContextual("from") {
+		c.errorAtCurrent("Expect 'from' after imported names.")
+	}
+	c.emitImport(c.modulePath())
+	for i, name := range names {
+		c.emitByteAt(OP_IMPORT_FROM, name)
+		c.emitByteAt(c.identifierConstant(name), name)
+		c.forgetGlobalSignature(aliases[i].Lexeme)
+		c.emitBytes(OP_DEFINE_GLOBAL, c.identifierConstant(aliases[i]))
+	}
+	c.emitByte(OP_POP)
+	c.consume(TOKEN_SEMICOLON, "Expect ';' after import.")
+}
+
+func (c *Compiler) modulePath() Token {
+	c.consume(TOKEN_STRING, "Expect module path string.")
+	return c.Ps.previous
+}
+
+// emitImport leaves the module on the stack, discarding the value its
+// top-level code returned.
+func (c *Compiler) emitImport(path Token) {
+	c.emitByteAt(OP_IMPORT, path)
+	c.emitByteAt(c.makeConstant(ObjVal{Object: CreateStringObj(path.Literal)}), path)
+	c.emitByte(OP_POP)
+}
+
+// exportDeclaration compiles a top-level declaration and makes its name
+// visible to modules that import this one.
+func (c *Compiler) exportDeclaration() {
+	if c.Type != TYPE_SCRIPT || c.ScopeDepth > 0 {
+		c.error("Can only export from top-level code.")
+	}
+	var name Token
+	switch {
+	case c.match(TOKEN_FUN):
+		name = c.Ps.current
+		c.functionDeclaration()
+	case c.match(TOKEN_CLASS):
+		name = c.Ps.current
+		c.classDeclaration()
+	case c.match(TOKEN_VAR):
+		name = c.Ps.current
+		c.varDeclaration()
+	case c.match(TOKEN_LET), c.match(TOKEN_CONST):
+		name = c.Ps.current
+		c.constDeclaration()
+	default:
+		c.errorAtCurrent("Expect declaration after 'export'.")
+		return
+	}
+	c.emitBytes(OP_EXPORT, c.identifierConstant(name))
+}
+
+// matchContextual consumes an identifier that acts as a keyword only in
+// certain positions, such as 'as' in an import.
+func (c *Compiler) matchContextual(word string) bool {
+	if !c.check(TOKEN_IDENTIFIER) || c.Ps.current.Lexeme != word {
+		return false
+	}
+	c.advance()
+	return true
+}
+
+// script returns the compiler for the module's top-level code, which owns
+// what is known about its globals.
+func (c *Compiler) script() *Compiler {
+	for c.Enclosing != nil {
+		c = c.Enclosing
+	}
+	return c
+}
+
+// inlineConstant looks up a global constant with a literal value.
+func (c *Compiler) inlineConstant(name Token) (Value, bool) {
+	val, ok := c.script().GlobalConstants[name.Lexeme]
+	return val, ok
+}
+
+func (c *Compiler) defineVariable(global byte) {
+	if c.ScopeDepth > 0 {
+		c.markInitialized()
+		return
+	}
+	c.emitBytes(OP_DEFINE_GLOBAL, global)
+}
+
+func (c *Compiler) markInitialized() {
+	if c.ScopeDepth == 0 {
+		return
+	}
+	c.Locals[c.LocalCount-1].depth =
+		c.ScopeDepth
+}
+
+func (c *Compiler) function(funct FunctionType) *ObjFunction {
+	comp := c.beginFunction(funct, c.Ps.previous.Lexeme)
+	comp.consume(TOKEN_LEFT_PAREN, "Expect '(' after function name.")
+	comp.parameters()
+	comp.consume(TOKEN_LEFT_BRACE, "Expect '{' before function body.")
+	comp.block()
+	return c.endFunction(comp)
+}
+
+// beginFunction returns a compiler for a function nested in this one.
+func (c *Compiler) beginFunction(funct FunctionType, name string) *Compiler {
+	comp := &Compiler{}
+	comp.initCompiler(funct)
+	comp.Sc = c.Sc
+	comp.Ps = c.Ps
+	comp.Enclosing = c
+	comp.CurrentClass = c.CurrentClass
+	comp.Debug = c.Debug
+	comp.File = c.File
+	comp.Function.source = c.File
+	comp.initRules()
+	functionName := CreateStringObj(name)
+	comp.Function.name = &functionName
+
+	comp.beginBlock()
+	return comp
+}
+
+// parameters compiles a parameter list up to and including the closing
+// parenthesis. Parameters may have defaults, `name = expr`, after which
+// every parameter needs one, and the list may end with a rest parameter,
+// `...name`.
+func (c *Compiler) parameters() {
+	hasDefault := false
+	for !c.check(TOKEN_RIGHT_PAREN) {
+		if c.match(TOKEN_ELLIPSIS) {
+			constant := c.parseVariable("Expect rest parameter name after '...'.")
+			c.defineVariable(constant)
+			c.Function.variadic = true
+			if !c.check(TOKEN_RIGHT_PAREN) {
+				c.errorAtCurrent("Rest parameter must be last.")
+			}
+			break
+		}
+		c.Function.arity++
+		if c.Function.arity > 255 {
+			c.errorAtCurrent("Can't have more than 255 parameters.")
+		}
+		constant := c.parseVariable("Expect parameter name.")
+		name := c.Ps.previous
+		c.Function.params = append(c.Function.params, name.Lexeme)
+		if c.match(TOKEN_EQUAL) {
+			hasDefault = true
+			c.defaultValue(c.Function.arity)
+		} else if hasDefault {
+			c.errorAt(name, "A parameter without a default can't follow one with a default.")
+		} else {
+			c.Function.minArity++
+		}
+		c.defineVariable(constant)
+		if !c.match(TOKEN_COMMA) {
+			break
+		}
+	}
+	c.consume(TOKEN_RIGHT_PAREN, "Expect ')' after parameters.")
+}
+
+// defaultValue compiles a parameter's default into the function's prologue,
+// so it is evaluated on each call that leaves the parameter out.
+func (c *Compiler) defaultValue(slot int) {
+	c.emitBytes(OP_SKIP_DEFAULT, byte(slot))
+	c.emitBytes(0xff, 0xff)
+	skipJump := c.Function.chunk.Count() - 2
+	c.expression()
+	c.emitBytes(OP_SET_LOCAL, byte(slot))
+	c.emitByte(OP_POP)
+	c.patchJump(skipJump)
+}
+
+// endFunction finishes comp and emits the closure that creates it at
+// runtime.
+func (c *Compiler) endFunction(comp *Compiler) *ObjFunction {
+	f := comp.endCompiler()
+	c.emitBytes(OP_CLOSURE, c.makeConstant(ObjVal{Object: f}))
+	for _, upvalue := range comp.Upvalues {
+		var isLocal byte
+		if upvalue.isLocal {
+			isLocal = 1
+		}
+		c.emitBytes(isLocal, upvalue.index)
+	}
+	return f
+}
+
+// funExpression compiles an anonymous `fun (params) { body }`.
+func (c *Compiler) funExpression(canAssign bool) {
+	comp := c.beginFunction(TYPE_FUNCTION, ANONYMOUS_FUNCTION)
+	comp.consume(TOKEN_LEFT_PAREN, "Expect '(' after 'fun'.")
+	comp.parameters()
+	comp.consume(TOKEN_LEFT_BRACE, "Expect '{' before function body.")
+	comp.block()
+	c.endFunction(comp)
+}
+
+// arrowFunction compiles `(params) => body` once the opening parenthesis
+// has been consumed. The body is a block or a single expression whose value
+// is returned.
+func (c *Compiler) arrowFunction() {
+	comp := c.beginFunction(TYPE_FUNCTION, ANONYMOUS_FUNCTION)
+	comp.parameters()
+	comp.arrowBody()
+	c.endFunction(comp)
+}
+
+// arrowParameter compiles `name => body`, with the parameter name just
+// consumed.
+func (c *Compiler) arrowParameter() {
+	comp := c.beginFunction(TYPE_FUNCTION, ANONYMOUS_FUNCTION)
+	comp.Function.arity = 1
+	comp.Function.minArity = 1
+	comp.Function.params = []string{c.Ps.previous.Lexeme}
+	comp.declareVariable()
+	comp.markInitialized()
+	comp.arrowBody()
+	c.endFunction(comp)
+}
+
+func (c *Compiler) arrowBody() {
+	c.consume(TOKEN_ARROW, "Expect '=>' after parameters.")
+	if c.match(TOKEN_LEFT_BRACE) {
+		c.block()
+		return
+	}
+	c.expression()
+	c.emitReturnValue()
+}
+
+// arrowAhead reports whether the parenthesis just consumed is closed by one
+// followed by '=>', making it an arrow function's parameter list. Defaults
+// inside the list may contain parentheses of their own.
+func (c *Compiler) arrowAhead() bool {
+	saved := c.Sc.snapshot()
+	defer func() { *c.Sc = saved }()
+
+	depth := 1
+	for tok := c.Ps.current; ; tok = c.Sc.scanToken() {
+		switch tok.Type {
+		case TOKEN_LEFT_PAREN:
+			depth++
+		case TOKEN_RIGHT_PAREN:
+			depth--
+		case TOKEN_EOF, TOKEN_ERROR:
+			return false
+		}
+		if depth == 0 {
+			return c.Sc.scanToken().Type == TOKEN_ARROW
+		}
+	}
+}
+
+func (c *Compiler) declareVariable() {
+	if c.ScopeDepth == 0 {
+		return
+	}
+	name := c.Ps.previous
+	for i := c.LocalCount - 1; i >= 0; i-- {
+		local := c.Locals[i]
+		if local.depth != -1 && local.depth < c.ScopeDepth {
+			break
+		}
+		if identifiersEqual(name, local.name) {
+			c.errorAtWithNote(name, "Already a variable with this name in this scope.",
+				fmt.Sprintf("'%s' was first declared on line %d.", local.name.Lexeme, local.name.Line))
+		}
+	}
+	c.addLocal(name)
+
+}
+
+func (c *Compiler) addLocal(name Token) {
+	if c.LocalCount == 255 {
+		c.error("Too many local variables in function.")
+		return
+	}
+	local := Local{}
+	local.depth = -1
+	local.name = name
+	c.Locals = append(c.Locals, local)
+	c.LocalCount++
+}
+
+func (c *Compiler) parseVariable(errorMessage string) byte {
+	c.consume(TOKEN_IDENTIFIER, errorMessage)
+	c.declareVariable()
+	if c.ScopeDepth > 0 {
+		return 0
+	}
+	c.forgetGlobalSignature(c.Ps.previous.Lexeme)
+	return c.identifierConstant(c.Ps.previous)
+}
+
+func (c *Compiler) identifierConstant(name Token) byte {
+	return c.makeConstant(ObjVal{Object: CreateStringObj(name.Lexeme)})
+}
+
+func (c *Compiler) synchronize() {
+	c.Ps.panicMode = false
+	for c.Ps.current.Type != TOKEN_EOF {
+		if c.Ps.previous.Type == TOKEN_SEMICOLON {
+			return
+		}
+		switch c.Ps.current.Type {
+		case TOKEN_CLASS, TOKEN_FUN, TOKEN_VAR, TOKEN_FOR, TOKEN_IF,
+			TOKEN_WHILE, TOKEN_PRINT, TOKEN_RETURN, TOKEN_BREAK, TOKEN_CONTINUE,
+			TOKEN_LET, TOKEN_CONST, TOKEN_TRY, TOKEN_THROW, TOKEN_IMPORT, TOKEN_EXPORT:
+			return
+		}
+		c.advance()
+	}
+}
+
+func (c *Compiler) statement() {
+	if c.match(TOKEN_PRINT) {
+		c.printStatement()
+	} else if c.match(TOKEN_RETURN) {
+		c.returnStatement()
+	} else if c.match(TOKEN_IF) {
+		c.ifStatement()
+	} else if c.match(TOKEN_WHILE) {
+		c.whileStatement(Token{})
+	} else if c.match(TOKEN_FOR) {
+		c.forStatement(Token{})
+	} else if c.match(TOKEN_TRY) {
+		c.tryStatement()
+	} else if c.match(TOKEN_THROW) {
+		c.throwStatement()
+	} else if c.match(TOKEN_BREAK) {
+		c.breakStatement()
+	} else if c.match(TOKEN_CONTINUE) {
+		c.continueStatement()
+	} else if c.check(TOKEN_IDENTIFIER) && c.Sc.peekToken().Type == TOKEN_COLON {
+		c.labeledStatement()
+	} else if c.match(TOKEN_LEFT_BRACE) {
+		c.beginBlock()
+		c.block()
+		c.endBlock()
+	} else {
+		c.expressionStatement()
+	}
+}
+
+func (c *Compiler) returnStatement() {
+	if c.Type == TYPE_SCRIPT {
+		c.error("Can't return from top-level code.")
+	}
+	if c.match(TOKEN_SEMICOLON) {
+		c.emitReturn()
+	} else {
+		if c.Type == TYPE_INITIALIZER {
+			c.error("Can't return a value from an initializer.")
+		}
+		c.expression()
+		c.consume(TOKEN_SEMICOLON, "Expect ';' after return value.")
+		c.emitReturnValue()
+	}
+}
+
+func (c *Compiler) throwStatement() {
+	keyword := c.Ps.previous
+	c.expression()
+	c.consume(TOKEN_SEMICOLON, "Expect ';' after thrown value.")
+	c.emitByteAt(OP_THROW, keyword)
+}
+
+func (c *Compiler) tryStatement() {
+	c.beginBlock()
+	c.emitByte(OP_NIL)
+	c.addLocal(syntheticToken(""))
+	c.markInitialized()
+	c.emitConstant(IntVal(COMPLETION_NORMAL))
+	c.addLocal(syntheticToken(""))
+	c.markInitialized()
+	try := &TryContext{
+		Enclosing:  c.CurrentTry,
+		ScopeDepth: c.ScopeDepth,
+		Slot:       c.LocalCount - 2,
+	}
+	c.CurrentTry = try
+
+	handlerJump := c.emitJump(OP_TRY)
+	c.consume(TOKEN_LEFT_BRACE, "Expect '{' after 'try'.")
+	c.beginBlock()
+	c.block()
+	c.endBlock()
+	c.emitByte(OP_END_TRY)
+	try.FinallyJumps = append(try.FinallyJumps, c.emitJump(OP_JUMP))
+
+	// The handler starts with the thrown value on top of the stack.
+	c.patchJump(handlerJump)
+	hasCatch := c.match(TOKEN_CATCH)
+	if hasCatch {
+		rethrowJump := c.emitJump(OP_TRY)
+		c.beginBlock()
+		c.consume(TOKEN_LEFT_PAREN, "Expect '(' after 'catch'.")
+		c.consume(TOKEN_IDENTIFIER, "Expect exception variable name.")
+		c.declareVariable()
+		c.markInitialized()
+		c.consume(TOKEN_RIGHT_PAREN, "Expect ')' after exception variable.")
+		c.consume(TOKEN_LEFT_BRACE, "Expect '{' before catch block.")
+		c.block()
+		c.emitByte(OP_END_TRY)
+		c.endBlock()
+		try.FinallyJumps = append(try.FinallyJumps, c.emitJump(OP_JUMP))
+		c.patchJump(rethrowJump)
+	}
+	c.emitByte(OP_SAVE_THROWN)
+	c.emitBytes(OP_SET_LOCAL, byte(try.Slot))
+	c.emitByte(OP_POP)
+	c.emitConstant(IntVal(COMPLETION_THROW))
+	c.emitBytes(OP_SET_LOCAL, byte(try.Slot+1))
+	c.emitByte(OP_POP)
+	if hasCatch {
+		c.emitByte(OP_POP) // The caught exception.
+	}
+
+	for _, jump := range try.FinallyJumps {
+		c.patchJump(jump)
+	}
+	c.CurrentTry = try.Enclosing
+	if c.match(TOKEN_FINALLY) {
+		c.consume(TOKEN_LEFT_BRACE, "Expect '{' after 'finally'.")
+		c.beginBlock()
+		c.block()
+		c.endBlock()
+	} else if !hasCatch {
+		c.error("Expect 'catch' or 'finally' after try block.")
+	}
+	c.completeTry(try)
+	c.endBlock()
+}
+
+// exitTry leaves the innermost try statement early through its finally
+// clause, stashing the value on top of the stack and the completion kind.
+func (c *Compiler) exitTry(kind int) {
+	try := c.CurrentTry
+	c.emitBytes(OP_SET_LOCAL, byte(try.Slot))
+	c.emitByte(OP_POP)
+	c.emitConstant(IntVal(kind))
+	c.emitBytes(OP_SET_LOCAL, byte(try.Slot+1))
+	c.emitByte(OP_POP)
+	c.discardLocals(try.ScopeDepth)
+	c.emitByte(OP_END_TRY)
+	try.FinallyJumps = append(try.FinallyJumps, c.emitJump(OP_JUMP))
+}
+
+// completeTry runs after the finally clause and carries on with whatever
+// left the try statement: a rethrow, a return or a jump out of a loop.
+func (c *Compiler) completeTry(try *TryContext) {
+	c.completeIf(try, COMPLETION_THROW, func() {
+		c.emitBytes(OP_GET_LOCAL, byte(try.Slot))
+		c.emitByte(OP_THROW)
+	})
+	if try.HasReturn {
+		c.completeIf(try, COMPLETION_RETURN, func() {
+			c.emitBytes(OP_GET_LOCAL, byte(try.Slot))
+			c.emitReturnValue()
+		})
+	}
+	for i, exit := range try.Exits {
+		c.completeIf(try, COMPLETION_JUMP+i, func() {
+			c.jumpOutOf(exit.Loop, exit.IsBreak)
+		})
+	}
+}
+
+func (c *Compiler) completeIf(try *TryContext, kind int, then func()) {
+	c.emitBytes(OP_GET_LOCAL, byte(try.Slot+1))
+	c.emitConstant(IntVal(kind))
+	c.emitByte(OP_EQUAL)
+	skipJump := c.emitJump(OP_JUMP_IF_FALSE)
+	c.emitByte(OP_POP)
+	then()
+	c.patchJump(skipJump)
+	c.emitByte(OP_POP)
+}
+
+// emitReturnValue returns the value on top of the stack, first running the
+// finally clause of any try statement it is inside.
+func (c *Compiler) emitReturnValue() {
+	if c.CurrentTry == nil {
+		c.emitByte(OP_RETURN)
+		return
+	}
+	c.CurrentTry.HasReturn = true
+	c.exitTry(COMPLETION_RETURN)
+}
+
+func (c *Compiler) labeledStatement() {
+	c.advance()
+	label := c.Ps.previous
+	c.advance()
+	for loop := c.CurrentLoop; loop != nil; loop = loop.Enclosing {
+		if identifiersEqual(label, loop.Label) {
+			c.errorAtWithNote(label, fmt.Sprintf("Label '%s' is already in use.", label.Lexeme),
+				fmt.Sprintf("'%s' labels an enclosing loop on line %d.", label.Lexeme, loop.Label.Line))
+		}
+	}
+	if c.match(TOKEN_WHILE) {
+		c.whileStatement(label)
+	} else if c.match(TOKEN_FOR) {
+		c.forStatement(label)
+	} else {
+		c.errorAtCurrent("Expect loop after label.")
+	}
+}
+
+func (c *Compiler) beginLoop(label Token, start int) *Loop {
+	loop := &Loop{
+		Enclosing:  c.CurrentLoop,
+		Label:      label,
+		Start:      start,
+		ScopeDepth: c.ScopeDepth,
+		Try:        c.CurrentTry,
+	}
+	c.CurrentLoop = loop
+	return loop
+}
+
+// endLoop patches the loop's break jumps to land on the next instruction.
+func (c *Compiler) endLoop() {
+	for _, jump := range c.CurrentLoop.BreakJumps {
+		c.patchJump(jump)
+	}
+	c.CurrentLoop = c.CurrentLoop.Enclosing
+}
+
+// targetLoop returns the loop a break or continue refers to, reading an
+// optional label after the keyword.
+func (c *Compiler) targetLoop(keyword Token) *Loop {
+	if c.match(TOKEN_IDENTIFIER) {
+		label := c.Ps.previous
+		for loop := c.CurrentLoop; loop != nil; loop = loop.Enclosing {
+			if identifiersEqual(label, loop.Label) {
+				return loop
+			}
+		}
+		c.error(fmt.Sprintf("No enclosing loop labeled '%s'.", label.Lexeme))
+		return nil
+	}
+	if c.CurrentLoop == nil {
+		c.errorAt(keyword, fmt.Sprintf("Can't use '%s' outside of a loop.", keyword.Lexeme))
+	}
+	return c.CurrentLoop
+}
+
+func (c *Compiler) breakStatement() {
+	keyword := c.Ps.previous
+	loop := c.targetLoop(keyword)
+	c.consume(TOKEN_SEMICOLON, "Expect ';' after 'break'.")
+	if loop != nil {
+		c.jumpOutOf(loop, true)
+	}
+}
+
+func (c *Compiler) continueStatement() {
+	keyword := c.Ps.previous
+	loop := c.targetLoop(keyword)
+	c.consume(TOKEN_SEMICOLON, "Expect ';' after 'continue'.")
+	if loop != nil {
+		c.jumpOutOf(loop, false)
+	}
+}
+
+// jumpOutOf breaks out of or continues loop, going through the finally
+// clause of any try statement in between.
+func (c *Compiler) jumpOutOf(loop *Loop, isBreak bool) {
+	if try := c.CurrentTry; try != loop.Try {
+		exit := TryExit{Loop: loop, IsBreak: isBreak}
+		index := slices.Index(try.Exits, exit)
+		if index == -1 {
+			index = len(try.Exits)
+			try.Exits = append(try.Exits, exit)
+		}
+		c.emitByte(OP_NIL)
+		c.exitTry(COMPLETION_JUMP + index)
+		return
+	}
+	c.discardLocals(loop.ScopeDepth)
+	if isBreak {
+		loop.BreakJumps = append(loop.BreakJumps, c.emitJump(OP_JUMP))
+	} else {
+		c.emitLoop(loop.Start)
+	}
+}
+
+func (c *Compiler) forStatement(label Token) {
+	c.beginBlock()
+	c.consume(TOKEN_LEFT_PAREN, "Expect '(' after 'for'.")
+	if c.match(TOKEN_SEMICOLON) {
+		// No init, just keep going
+	} else if c.match(TOKEN_VAR) {
+		c.varDeclaration()
+	} else {
+		c.expressionStatement()
+	}
+
+	loopStart := c.Function.chunk.Count()
+	exitJump := -1
+	if !c.match(TOKEN_SEMICOLON) {
+		c.expression()
+		c.consume(TOKEN_SEMICOLON, "Expect ';' after loop condition.")
+
+		exitJump = c.emitJump(OP_JUMP_IF_FALSE)
+		c.emitByte(OP_POP)
+
+	}
+	if !c.match(TOKEN_RIGHT_PAREN) {
+		bodyJump := c.emitJump(OP_JUMP)
+		incrementStart := c.Function.chunk.Count()
+		c.expression()
+		c.emitByte(OP_POP)
+		c.consume(TOKEN_RIGHT_PAREN, "Expect ')' after for clauses.")
+
+		c.emitLoop(loopStart)
+		loopStart = incrementStart
+		c.patchJump(bodyJump)
+	}
+
+	c.beginLoop(label, loopStart)
+	c.statement()
+	c.emitLoop(loopStart)
+
+	if exitJump != -1 {
+		c.patchJump(exitJump)
+		c.emitByte(OP_POP) // Condition.
+	}
+	c.endLoop()
+	c.endBlock()
+}
+
+func (c *Compiler) whileStatement(label Token) {
+	loopStart := c.Function.chunk.Count()
+	c.consume(TOKEN_LEFT_PAREN, "Expect '(' after 'while'.")
+	c.expression()
+	c.consume(TOKEN_RIGHT_PAREN, "Expect '(' after condition.")
+
+	exitJump := c.emitJump(OP_JUMP_IF_FALSE)
+	c.emitByte(OP_POP)
+	c.beginLoop(label, loopStart)
+	c.statement()
+	c.emitLoop(loopStart)
+	c.patchJump(exitJump)
+	c.emitByte(OP_POP)
+	c.endLoop()
+}
+
+func (c *Compiler) emitLoop(loopStart int) {
+	c.emitByte(OP_LOOP)
+	offset := c.Function.chunk.Count() - loopStart + 2
+	if offset > math.MaxUint16 {
+		c.error("Loop body too large.")
+	}
+	c.emitByte(byte((offset >> 8) & 0xff))
+	c.emitByte(byte(offset & 0xff))
+
+}
+
+func (c *Compiler) ifStatement() {
+	c.consume(TOKEN_LEFT_PAREN, "Expect '(' after 'if'.")
+	c.expression()
+	c.consume(TOKEN_RIGHT_PAREN, "Expect '(' after condition.")
+
+	thenJump := c.emitJump(OP_JUMP_IF_FALSE)
+	c.emitByte(OP_POP)
+
+	c.statement()
+	elseJump := c.emitJump(OP_JUMP)
+
+	c.patchJump(thenJump)
+	c.emitByte(OP_POP)
+	if c.match(TOKEN_ELSE) {
+		c.statement()
+	}
+	c.patchJump(elseJump)
+}
+
+func (c *Compiler) patchJump(offset int) {
+	jump := c.Function.chunk.Count() - offset - 2
+	if jump > math.MaxUint16 {
+		c.error("Too many instructions to jump over")
+	}
+	c.Function.chunk.Code[offset] = byte((jump >> 8) & 0xff)
+	c.Function.chunk.Code[offset+1] = byte(jump & 0xff)
+}
+
+func (c *Compiler) emitJump(instruction byte) int {
+	c.emitByte(instruction)
+	c.emitByte(0xff)
+	c.emitByte(0xff)
+
+	return c.Function.chunk.Count() - 2
+}
+
+func (c *Compiler) block() {
+	for !c.check(TOKEN_RIGHT_BRACE) && !c.check(TOKEN_EOF) {
+		c.declaration()
+	}
+	c.consume(TOKEN_RIGHT_BRACE, "Expect '}' after block.")
+}
+
+func (c *Compiler) beginBlock() {
+	c.ScopeDepth += 1
+}
+
+func (c *Compiler) endBlock() {
+	c.ScopeDepth -= 1
+	c.discardLocals(c.ScopeDepth)
+	for c.LocalCount > 0 && c.Locals[c.LocalCount-1].depth > c.ScopeDepth {
+		c.LocalCount--
+	}
+	c.Locals = c.Locals[:c.LocalCount]
+}
+
+// discardLocals emits code removing the locals declared deeper than depth
+// from the stack, closing any that were captured by a closure. The compiler's
+// own bookkeeping is left alone so break and continue can use it mid-block.
+func (c *Compiler) discardLocals(depth int) {
+	pops := 0
+	for i := c.LocalCount - 1; i >= 0 && c.Locals[i].depth > depth; i-- {
+		if c.Locals[i].isCaptured {
+			c.emitPops(pops)
+			pops = 0
+			c.emitByte(OP_CLOSE_UPVALUE)
+		} else {
+			pops++
+		}
+	}
+	c.emitPops(pops)
+}
+
+// emitPops discards count values from the stack, batching them into a
+// single OP_POPN when there is more than one.
+func (c *Compiler) emitPops(count int) {
+	switch count {
+	case 0:
+	case 1:
+		c.emitByte(OP_POP)
+	default:
+		c.emitBytes(OP_POPN, byte(count))
+	}
+}
+
+func (c *Compiler) match(tokType TokenType) bool {
+	if !c.check(tokType) {
+		return false
+	}
+	c.advance()
+	return true
+}
+
+func (c *Compiler) check(tokType TokenType) bool {
+	return c.Ps.current.Type == tokType
+}
+
+func (c *Compiler) printStatement() {
+	c.expression()
+	c.consume(TOKEN_SEMICOLON, "Expect ';' after value.")
+	c.emitByte(OP_PRINT)
+}
+
+func (c *Compiler) expressionStatement() {
+	c.expression()
+	if c.ReplMode && c.Type == TYPE_SCRIPT && c.ScopeDepth == 0 {
+		// The REPL echoes top-level expressions and lets the last one
+		// omit its semicolon.
+		if !c.check(TOKEN_EOF) {
+			c.consume(TOKEN_SEMICOLON, "Expect ';' after expression")
+		}
+		c.emitByte(OP_PRINT)
+		return
+	}
+	c.consume(TOKEN_SEMICOLON, "Expect ';' after expression")
+	if c.Type == TYPE_SCRIPT && c.ScopeDepth == 0 && c.check(TOKEN_EOF) {
+		c.resultOnStack = true
+		return
+	}
+	c.emitByte(OP_POP)
+}
+
+func (c *Compiler) expression() {
+	c.parsePrecedence(PREC_ASSIGNMENT)
+}
+
+func (c *Compiler) parsePrecedence(prec Precedence) {
+	c.advance()
+	prefixRule := c.getRule(c.Ps.previous.Type).prefix
+	if prefixRule == nil {
+		c.error("Expect expression.")
+		return
+	}
+	canAssign := prec <= PREC_ASSIGNMENT
+	prefixRule(canAssign)
+	for prec <= c.getRule(c.Ps.current.Type).precedence {
+		c.advance()
+		infixRule := c.getRule(c.Ps.previous.Type).infix
+		infixRule(canAssign)
+	}
+
+	if canAssign && (c.match(TOKEN_EQUAL) || c.matchCompound()) {
+		c.error("Invalid assignment target.")
+	}
+}
+
+func (c *Compiler) number(canAssign bool) {
+	val, err := parseNumber(c.Ps.previous.Lexeme)
+	if err != nil {
+		c.error(err.Error())
+		return
+	}
+	c.emitConstant(val)
+}
+
+// literalValue returns the value of a literal token.
+func literalValue(tok Token) (Value, bool) {
+	switch tok.Type {
+	case TOKEN_NUMBER:
+		val, err := parseNumber(tok.Lexeme)
+		return val, err == nil
+	case TOKEN_STRING:
+		return ObjVal{Object: CreateStringObj(tok.Literal)}, true
+	case TOKEN_TRUE:
+		return BoolVal(true), true
+	case TOKEN_FALSE:
+		return BoolVal(false), true
+	case TOKEN_NIL:
+		return NilVal{}, true
+	}
+	return nil, false
+}
+
+func (c *Compiler) literal(canAssign bool) {
+	switch c.Ps.previous.Type {
+	case TOKEN_NIL:
+		c.emitByte(OP_NIL)
+	case TOKEN_FALSE:
+		c.emitByte(OP_FALSE)
+	case TOKEN_TRUE:
+		c.emitByte(OP_TRUE)
+	}
+}
+
+func (c *Compiler) and_(canAssign bool) {
+	endJump := c.emitJump(OP_JUMP_IF_FALSE)
+	c.emitByte(OP_POP)
+	c.parsePrecedence(PREC_AND)
+
+	c.patchJump(endJump)
+}
+
+func (c *Compiler) or_(canAssign bool) {
+	elseJump := c.emitJump(OP_JUMP_IF_FALSE)
+	endJump := c.emitJump(OP_JUMP)
+
+	c.patchJump(elseJump)
+	c.emitByte(OP_POP)
+
+	c.parsePrecedence(PREC_OR)
+	c.patchJump(endJump)
+}
+
+func (c *Compiler) binary(canAssign bool) {
+	operator := c.Ps.previous
+	rule := c.getRule(operator.Type)
+	precedence := rule.precedence + 1
+	if operator.Type == TOKEN_STAR_STAR {
+		// Right-associative, and the exponent may be negated: 2 ** -1.
+		precedence = PREC_UNARY
+	}
+	c.parsePrecedence(precedence)
+	switch operator.Type {
+	case TOKEN_PLUS:
+		c.emitByteAt(OP_ADD, operator)
+	case TOKEN_MINUS:
+		c.emitByteAt(OP_SUBSTRACT, operator)
+	case TOKEN_STAR:
+		c.emitByteAt(OP_MULTIPLY, operator)
+	case TOKEN_SLASH:
+		c.emitByteAt(OP_DIVIDE, operator)
+	case TOKEN_TILDE_SLASH:
+		c.emitByteAt(OP_FLOOR_DIVIDE, operator)
+	case TOKEN_PERCENT:
+		c.emitByteAt(OP_MODULO, operator)
+	case TOKEN_STAR_STAR:
+		c.emitByteAt(OP_POWER, operator)
+	case TOKEN_AMPERSAND:
+		c.emitByteAt(OP_BIT_AND, operator)
+	case TOKEN_PIPE:
+		c.emitByteAt(OP_BIT_OR, operator)
+	case TOKEN_CARET:
+		c.emitByteAt(OP_BIT_XOR, operator)
+	case TOKEN_LESS_LESS:
+		c.emitByteAt(OP_SHIFT_LEFT, operator)
+	case TOKEN_GREATER_GREATER:
+		c.emitByteAt(OP_SHIFT_RIGHT, operator)
+	case TOKEN_GREATER:
+		c.emitByteAt(OP_GREATER, operator)
+	case TOKEN_LESS:
+		c.emitByteAt(OP_LESS, operator)
+	case TOKEN_EQUAL_EQUAL:
+		c.emitByteAt(OP_EQUAL, operator)
+	case TOKEN_LESS_EQUAL:
+		c.emitByteAt(OP_GREATER, operator)
+		c.emitByteAt(OP_NOT, operator)
+	case TOKEN_BANG_EQUAL:
+		c.emitByteAt(OP_EQUAL, operator)
+		c.emitByteAt(OP_NOT, operator)
+	case TOKEN_GREATER_EQUAL:
+		c.emitByteAt(OP_LESS, operator)
+		c.emitByteAt(OP_NOT, operator)
+
+	default:
+		return // Unreachable.
+	}
+}
+
+func (c *Compiler) call(canAssign bool) {
+	paren := c.Ps.previous
+	callee := c.callee
+	c.callee = nil
+	argCount, names := c.argumentList()
+	if callee != nil {
+		script := c.script()
+		script.pendingCalls = append(script.pendingCalls, pendingCall{callee, paren, int(argCount), names})
+	}
+	if len(names) == 0 {
+		c.emitByteAt(OP_CALL, paren)
+		c.emitByteAt(argCount, paren)
+		return
+	}
+	c.emitByteAt(OP_CALL_NAMED, paren)
+	c.emitByteAt(argCount, paren)
+	c.emitByteAt(byte(len(names)), paren)
+	for _, name := range names {
+		c.emitByteAt(c.identifierConstant(name), name)
+	}
+}
+
+// checkPendingCalls checks the calls recorded while compiling the script
+// against the functions they call, skipping any whose binding was assigned.
+// The errors are put back in source order among the others.
+func (c *Compiler) checkPendingCalls() {
+	reported := len(c.Ps.diagnostics)
+	for _, call := range c.pendingCalls {
+		if call.callee.assigned {
+			continue
+		}
+		c.Ps.panicMode = false
+		c.checkCall(call.callee.function, call.paren, call.argCount, call.names)
+	}
+	c.Ps.panicMode = false
+	if len(c.Ps.diagnostics) > reported {
+		slices.SortStableFunc(c.Ps.diagnostics, func(a, b Diagnostic) int {
+			return a.Offset - b.Offset
+		})
+	}
+}
+
+// checkCall reports at compile time the argument errors the VM would raise
+// when calling a function whose declaration is known.
+func (c *Compiler) checkCall(callee *ObjFunction, paren Token, argCount int, names []Token) {
+	for i, name := range names {
+		if slices.ContainsFunc(names[:i], func(other Token) bool { return identifiersEqual(name, other) }) {
+			return // argumentList has reported the repeated name.
+		}
+	}
+	positional := argCount - len(names)
+	if msg := arityError(callee, positional, len(names) > 0); msg != "" {
+		c.errorAt(paren, msg)
+		return
+	}
+	given := make([]bool, callee.arity)
+	for i := 0; i < min(positional, callee.arity); i++ {
+		given[i] = true
+	}
+	for _, name := range names {
+		index := slices.Index(callee.params, name.Lexeme)
+		if index == -1 {
+			c.errorAt(name, fmt.Sprintf("%s() has no parameter named '%s'.", functionName(callee), name.Lexeme))
+			return
+		}
+		if given[index] {
+			c.errorAt(name, fmt.Sprintf("%s() got more than one value for '%s'.", functionName(callee), name.Lexeme))
+			return
+		}
+		given[index] = true
+	}
+	for i := 0; i < callee.minArity; i++ {
+		if !given[i] {
+			c.errorAt(paren, fmt.Sprintf("%s() is missing argument '%s'.", functionName(callee), callee.params[i]))
+			return
+		}
+	}
+}
+
+func (c *Compiler) list(canAssign bool) {
+	itemCount := 0
+	if !c.check(TOKEN_RIGHT_BRACKET) {
+		for {
+			if c.check(TOKEN_RIGHT_BRACKET) {
+				break // Trailing comma.
+			}
+			c.expression()
+			if itemCount == 255 {
+				c.error("Can't have more than 255 items in a list literal.")
+			}
+			itemCount++
+			if !c.match(TOKEN_COMMA) {
+				break
+			}
+		}
+	}
+	c.consume(TOKEN_RIGHT_BRACKET, "Expect ']' after list items.")
+	c.emitBytes(OP_BUILD_LIST, byte(itemCount))
+}
+
+func (c *Compiler) mapLiteral(canAssign bool) {
+	entryCount := 0
+	for !c.check(TOKEN_RIGHT_BRACE) {
+		c.expression()
+		c.consume(TOKEN_COLON, "Expect ':' after map key.")
+		c.expression()
+		if entryCount == 255 {
+			c.error("Can't have more than 255 entries in a map literal.")
+		}
+		entryCount++
+		if !c.match(TOKEN_COMMA) {
+			break
+		}
+	}
+	c.consume(TOKEN_RIGHT_BRACE, "Expect '}' after map entries.")
+	c.emitBytes(OP_BUILD_MAP, byte(entryCount))
+}
+
+func (c *Compiler) subscript(canAssign bool) {
+	bracket := c.Ps.previous
+	c.expression()
+	c.consume(TOKEN_RIGHT_BRACKET, "Expect ']' after index.")
+
+	if canAssign && c.match(TOKEN_EQUAL) {
+		c.expression()
+		c.emitByteAt(OP_INDEX_SET, bracket)
+	} else {
+		c.emitByteAt(OP_INDEX_GET, bracket)
+	}
+}
+
+func (c *Compiler) dot(canAssign bool) {
+	c.consume(TOKEN_IDENTIFIER, "Expect property name after '.'.")
+	property := c.Ps.previous
+	name := c.identifierConstant(property)
+
+	if canAssign && c.match(TOKEN_EQUAL) {
+		c.expression()
+		c.emitByteAt(OP_SET_PROPERTY, property)
+		c.emitByteAt(name, property)
+	} else {
+		c.emitBytes(OP_GET_PROPERTY, name)
+	}
+}
+
+// argumentList compiles call arguments, returning how many there are and
+// the names of the trailing ones passed as `name: value`.
+func (c *Compiler) argumentList() (byte, []Token) {
+	argCount := 0
+	var names []Token
+	if !c.check(TOKEN_RIGHT_PAREN) {
+		for {
+			if c.check(TOKEN_IDENTIFIER) && c.Sc.peekToken().Type == TOKEN_COLON {
+				c.advance()
+				name := c.Ps.previous
+				c.advance()
+				for _, other := range names {
+					if identifiersEqual(name, other) {
+						c.errorAt(name, fmt.Sprintf("Argument '%s' is passed more than once.", name.Lexeme))
+					}
+				}
+				names = append(names, name)
+			} else if len(names) > 0 {
+				c.errorAtCurrent("Positional arguments must come before named arguments.")
+			}
+			c.expression()
+			if argCount == 255 {
+				c.error("Can't have more than 255 arguments.")
+			}
+			argCount++
+			if !c.match(TOKEN_COMMA) {
+				break
+			}
+		}
+	}
+	c.consume(TOKEN_RIGHT_PAREN, "Expect ')' after arguments.")
+	return byte(argCount), names
+}
+
+func (c *Compiler) str(canAssign bool) {
+	c.emitConstant(ObjVal{Object: CreateStringObj(c.Ps.previous.Literal)})
+}
+
+// interpolation compiles "a${x}b${y}c" as "a" + str(x) + "b" + str(y) + "c".
+func (c *Compiler) interpolation(canAssign bool) {
+	c.str(false)
+	for {
+		if (c.check(TOKEN_STRING) || c.check(TOKEN_INTERPOLATION)) && strings.HasPrefix(c.Ps.current.Lexeme, "}") {
+			c.errorAtCurrent("Expect expression in string interpolation.")
+		}
+		c.expression()
+		c.emitByte(OP_TO_STRING)
+		c.emitByte(OP_ADD)
+		if c.match(TOKEN_INTERPOLATION) {
+			c.str(false)
+			c.emitByte(OP_ADD)
+			continue
+		}
+		c.consume(TOKEN_STRING, "Expect '}' after interpolated expression.")
+		if c.Ps.previous.Literal != "" {
+			c.str(false)
+			c.emitByte(OP_ADD)
+		}
+		return
+	}
+}
+
+func (c *Compiler) grouping(canAssign bool) {
+	if c.arrowAhead() {
+		c.arrowFunction()
+		return
+	}
+	c.expression()
+	c.consume(TOKEN_RIGHT_PAREN, "Expected ')' after expression ")
+}
+
+func (c *Compiler) unary(canAssign bool) {
+	operator := c.Ps.previous
+	c.parsePrecedence(PREC_UNARY)
+	switch operator.Type {
+	case TOKEN_MINUS:
+		c.emitByteAt(OP_NEGATE, operator)
+	case TOKEN_BANG:
+		c.emitByteAt(OP_NOT, operator)
+	case TOKEN_TILDE:
+		c.emitByteAt(OP_BIT_NOT, operator)
+	default:
+		return
+	}
+}
+
+func (c *Compiler) this_(canAssign bool) {
+	if c.CurrentClass == nil {
+		c.error("Can't use 'this' outside of a class.")
+		return
+	}
+	c.variable(false)
+}
+
+func (c *Compiler) super_(canAssign bool) {
+	if c.CurrentClass == nil {
+		c.error("Can't use 'super' outside of a class.")
+	} else if !c.CurrentClass.HasSuperclass {
+		c.error("Can't use 'super' in a class with no superclass.")
+	}
+
+	c.consume(TOKEN_DOT, "Expect '.' after 'super'.")
+	c.consume(TOKEN_IDENTIFIER, "Expect superclass method name.")
+	name := c.identifierConstant(c.Ps.previous)
+
+	c.namedVariable(syntheticToken("this"), false)
+	c.namedVariable(syntheticToken("super"), false)
+	c.emitBytes(OP_GET_SUPER, name)
+}
+
+func (c *Compiler) variable(canAssign bool) {
+	if c.check(TOKEN_ARROW) {
+		c.arrowParameter()
+		return
+	}
+	c.namedVariable(c.Ps.previous, canAssign)
+}
+
+// variableRef says how compiled code reads and writes a named variable.
+// A global constant with a literal value is read with OP_CONSTANT.
+type variableRef struct {
+	getOp     byte
+	setOp     byte
+	arg       int
+	isConst   bool
+	signature *signature
+}
+
+func (c *Compiler) resolveVariable(name Token) variableRef {
+	if arg := c.resolveLocal(name); arg != -1 {
+		local := c.Locals[arg]
+		return variableRef{OP_GET_LOCAL, OP_SET_LOCAL, arg, local.isConst, local.signature}
+	}
+	if arg := c.resolveUpvalue(name); arg != -1 {
+		return variableRef{OP_GET_UPVALUE, OP_SET_UPVALUE, arg, c.Upvalues[arg].isConst, nil}
+	}
+	if val, ok := c.inlineConstant(name); ok {
+		return variableRef{getOp: OP_CONSTANT, arg: int(c.makeConstant(val)), isConst: true}
+	}
+	arg := int(c.identifierConstant(name))
+	return variableRef{OP_GET_GLOBAL, OP_SET_GLOBAL, arg, false, c.script().GlobalSignatures[name.Lexeme]}
+}
+
+// compoundOps maps each compound assignment operator to the arithmetic it
+// applies.
+var compoundOps = map[TokenType]byte{
+	TOKEN_PLUS_EQUAL:    OP_ADD,
+	TOKEN_MINUS_EQUAL:   OP_SUBSTRACT,
+	TOKEN_STAR_EQUAL:    OP_MULTIPLY,
+	TOKEN_SLASH_EQUAL:   OP_DIVIDE,
+	TOKEN_PERCENT_EQUAL: OP_MODULO,
+}
+
+func (c *Compiler) matchCompound() bool {
+	if _, ok := compoundOps[c.Ps.current.Type]; !ok {
+		return false
+	}
+	c.advance()
+	return true
+}
+
+// namedVariable compiles a read of name, or an assignment to it with '=', a
+// compound operator such as '+=', or a postfix '++' or '--'. Each reads
+// and writes the variable once.
+func (c *Compiler) namedVariable(name Token, canAssign bool) {
+	ref := c.resolveVariable(name)
+
+	switch {
+	case canAssign && c.match(TOKEN_EQUAL):
+		c.assignTo(name, ref)
+		c.expression()
+		c.emitVariable(ref.setOp, ref.arg, name)
+	case canAssign && c.matchCompound():
+		operator := c.Ps.previous
+		c.assignTo(name, ref)
+		c.emitVariable(ref.getOp, ref.arg, name)
+		c.expression()
+		c.emitByteAt(compoundOps[operator.Type], operator)
+		c.emitVariable(ref.setOp, ref.arg, name)
+	case c.match(TOKEN_PLUS_PLUS) || c.match(TOKEN_MINUS_MINUS):
+		// The copy left below the updated value is the old value, which
+		// is the result of a postfix increment.
+		operator := c.Ps.previous
+		c.assignTo(name, ref)
+		c.emitVariable(ref.getOp, ref.arg, name)
+		c.emitByteAt(OP_DUP, operator)
+		c.emitIncrement(operator)
+		c.emitVariable(ref.setOp, ref.arg, name)
+		c.emitByteAt(OP_POP, operator)
+	default:
+		c.emitVariable(ref.getOp, ref.arg, name)
+		if c.check(TOKEN_LEFT_PAREN) {
+			c.callee = ref.signature
+		}
+	}
+}
+
+// prefixIncrement compiles '++name' or '--name', whose value is the
+// updated variable.
+func (c *Compiler) prefixIncrement(canAssign bool) {
+	operator := c.Ps.previous
+	c.consume(TOKEN_IDENTIFIER, fmt.Sprintf("Expect variable name after '%s'.", operator.Lexeme))
+	name := c.Ps.previous
+	ref := c.resolveVariable(name)
+	c.assignTo(name, ref)
+	c.emitVariable(ref.getOp, ref.arg, name)
+	c.emitIncrement(operator)
+	c.emitVariable(ref.setOp, ref.arg, name)
+}
+
+func (c *Compiler) emitIncrement(operator Token) {
+	c.emitByteAt(OP_CONSTANT, operator)
+	c.emitByteAt(c.makeConstant(IntVal(1)), operator)
+	if operator.Type == TOKEN_PLUS_PLUS {
+		c.emitByteAt(OP_ADD, operator)
+	} else {
+		c.emitByteAt(OP_SUBSTRACT, operator)
+	}
+}
+
+func (c *Compiler) emitVariable(op byte, arg int, name Token) {
+	c.emitByteAt(op, name)
+	c.emitByteAt(byte(arg), name)
+}
+
+// assignTo checks that the variable can be assigned before code to do so
+// is compiled.
+func (c *Compiler) assignTo(name Token, ref variableRef) {
+	if ref.isConst {
+		c.errorAt(name, fmt.Sprintf("Can't assign to constant '%s'.", name.Lexeme))
+	}
+	c.forgetSignature(name, ref.getOp, ref.arg)
+}
+
+// forgetSignature stops checking calls to a function variable once it is
+// assigned anywhere, since it may no longer hold that function when any of
+// them run.
+func (c *Compiler) forgetSignature(name Token, getOp byte, arg int) {
+	switch getOp {
+	case OP_GET_LOCAL:
+		if sig := c.Locals[arg].signature; sig != nil {
+			sig.assigned = true
+		}
+	case OP_GET_UPVALUE:
+		for enclosing := c.Enclosing; enclosing != nil; enclosing = enclosing.Enclosing {
+			if local := enclosing.resolveLocal(name); local != -1 {
+				if sig := enclosing.Locals[local].signature; sig != nil {
+					sig.assigned = true
+				}
+				return
+			}
+		}
+	case OP_GET_GLOBAL:
+		c.forgetGlobalSignature(name.Lexeme)
+	}
+}
+
+// forgetGlobalSignature is forgetSignature for a global, which is also
+// reassigned when it is declared again.
+func (c *Compiler) forgetGlobalSignature(name string) {
+	script := c.script()
+	if sig := script.GlobalSignatures[name]; sig != nil {
+		sig.assigned = true
+		delete(script.GlobalSignatures, name)
+	}
+}
+
+func (c *Compiler) resolveLocal(name Token) int {
+	for i := c.LocalCount - 1; i >= 0; i-- {
+		local := c.Locals[i]
+		if identifiersEqual(name, local.name) {
+			if local.depth == -1 {
+				c.error("Can't read local variable in its own initializer.")
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+func (c *Compiler) resolveUpvalue(name Token) int {
+	if c.Enclosing == nil {
+		return -1
+	}
+
+	local := c.Enclosing.resolveLocal(name)
+	if local != -1 {
+		c.Enclosing.Locals[local].isCaptured = true
+		return c.addUpvalue(byte(local), true, c.Enclosing.Locals[local].isConst)
+	}
+
+	upvalue := c.Enclosing.resolveUpvalue(name)
+	if upvalue != -1 {
+		return c.addUpvalue(byte(upvalue), false, c.Enclosing.Upvalues[upvalue].isConst)
+	}
+	return -1
+}
+
+func (c *Compiler) addUpvalue(index byte, isLocal bool, isConst bool) int {
+	for i, upvalue := range c.Upvalues {
+		if upvalue.index == index && upvalue.isLocal == isLocal {
+			return i
+		}
+	}
+
+	if len(c.Upvalues) == 255 {
+		c.error("Too many closure variables in function.")
+		return 0
+	}
+	c.Upvalues = append(c.Upvalues, Upvalue{index: index, isLocal: isLocal, isConst: isConst})
+	c.Function.upvalueCount++
+	return c.Function.upvalueCount - 1
+}
+
+func (c *Compiler) emitConstant(val Value) {
+	c.emitBytes(OP_CONSTANT, c.makeConstant(val))
+}
+
+func (c *Compiler) makeConstant(val Value) byte {
+	constant := c.Function.chunk.AddConstant(val)
+	if constant > 255 {
+		c.error("Too many constants in one chunk")
+		return 0
+	}
+	return byte(constant)
+
+}
+
+func (c *Compiler) emitByte(b byte) {
+	c.emitByteAt(b, c.Ps.previous)
+}
+
+// emitByteAt attributes b to tok, so runtime errors raised by the instruction
+// point at that token rather than at the last one parsed.
+func (c *Compiler) emitByteAt(b byte, tok Token) {
+	c.Function.chunk.Write(b, tok.Span())
+}
+
+func (c *Compiler) endCompiler() *ObjFunction {
+	function := c.Function
+	c.emitReturn()
+	if c.Debug.PrintCode {
+		if !c.Ps.hadError {
+			funcName := "<script>"
+			if function.name != nil {
+				funcName = function.name.Characters
+			}
+			DisassembleChunk(c.Debug.writer(), &c.Function.chunk, funcName)
+		}
+	}
+
+	return function
+
+}
+
+func (c *Compiler) emitReturn() {
+	if c.resultOnStack {
+		c.emitByte(OP_RETURN)
+		return
+	}
+	if c.Type == TYPE_INITIALIZER {
+		c.emitBytes(OP_GET_LOCAL, 0)
+	} else {
+		c.emitByte(OP_NIL)
+	}
+	c.emitReturnValue()
+}
+
+func (c *Compiler) emitBytes(b1, b2 byte) {
+	c.emitByte(b1)
+	c.emitByte(b2)
+}
+
+func (c *Compiler) consume(tokT TokenType, message string) {
+	if c.Ps.current.Type == tokT {
+		c.advance()
+		return
+	}
+	c.errorAtCurrent(message)
+}
+
+func (c *Compiler) advance() {
+	c.Ps.previous = c.Ps.current
+	for {
+		c.Ps.current = c.Sc.scanToken()
+		if c.Ps.current.Type != TOKEN_ERROR {
+			break
+		}
+		c.errorAtCurrent(c.Ps.current.Lexeme)
+	}
+}
+
+func (c *Compiler) errorAtCurrent(message string) {
+	c.errorAt(c.Ps.current, message)
+}
+
+func (c *Compiler) error(message string) {
+	c.errorAt(c.Ps.previous, message)
+}
+
+func (c *Compiler) errorAt(tok Token, message string) {
+	c.errorAtWithNote(tok, message, "")
+}
+
+func (c *Compiler) errorAtWithNote(tok Token, message, note string) {
+	if c.Ps.panicMode {
+		return
+	}
+	c.Ps.panicMode = true
+	c.Ps.hadError = true
+	if c.Ps.maxErrors > 0 && len(c.Ps.diagnostics) >= c.Ps.maxErrors {
+		c.Ps.truncated = true
+		return
+	}
+	diagnostic := Diagnostic{
+		Span:    tok.Span(),
+		Source:  c.File.Name,
+		Text:    lineText(c.File.Text, tok.Offset),
+		Message: message,
+		Note:    note,
+	}
+	switch tok.Type {
+	case TOKEN_EOF:
+		diagnostic.AtEnd = true
+	case TOKEN_ERROR:
+	default:
+		diagnostic.Lexeme = tok.Lexeme
+	}
+	c.Ps.diagnostics = append(c.Ps.diagnostics, diagnostic)
+}
+
+func (c *Compiler) initRules() {
+	c.rules = map[TokenType]ParseRule{
+		TOKEN_LEFT_PAREN:      {c.grouping, c.call, PREC_CALL},
+		TOKEN_RIGHT_PAREN:     {nil, nil, PREC_NONE},
+		TOKEN_LEFT_BRACE:      {c.mapLiteral, nil, PREC_NONE},
+		TOKEN_RIGHT_BRACE:     {nil, nil, PREC_NONE},
+		TOKEN_LEFT_BRACKET:    {c.list, c.subscript, PREC_CALL},
+		TOKEN_RIGHT_BRACKET:   {nil, nil, PREC_NONE},
+		TOKEN_COMMA:           {nil, nil, PREC_NONE},
+		TOKEN_COLON:           {nil, nil, PREC_NONE},
+		TOKEN_DOT:             {nil, c.dot, PREC_CALL},
+		TOKEN_MINUS:           {c.unary, c.binary, PREC_TERM},
+		TOKEN_PLUS:            {nil, c.binary, PREC_TERM},
+		TOKEN_SEMICOLON:       {nil, nil, PREC_NONE},
+		TOKEN_SLASH:           {nil, c.binary, PREC_FACTOR},
+		TOKEN_STAR:            {nil, c.binary, PREC_FACTOR},
+		TOKEN_TILDE_SLASH:     {nil, c.binary, PREC_FACTOR},
+		TOKEN_PERCENT:         {nil, c.binary, PREC_FACTOR},
+		TOKEN_PLUS_PLUS:       {c.prefixIncrement, nil, PREC_NONE},
+		TOKEN_MINUS_MINUS:     {c.prefixIncrement, nil, PREC_NONE},
+		TOKEN_STAR_STAR:       {nil, c.binary, PREC_POWER},
+		TOKEN_AMPERSAND:       {nil, c.binary, PREC_BIT_AND},
+		TOKEN_PIPE:            {nil, c.binary, PREC_BIT_OR},
+		TOKEN_CARET:           {nil, c.binary, PREC_BIT_XOR},
+		TOKEN_TILDE:           {c.unary, nil, PREC_NONE},
+		TOKEN_LESS_LESS:       {nil, c.binary, PREC_SHIFT},
+		TOKEN_GREATER_GREATER: {nil, c.binary, PREC_SHIFT},
+		TOKEN_BANG:            {c.unary, nil, PREC_NONE},
+		TOKEN_BANG_EQUAL:      {nil, c.binary, PREC_EQUALITY},
+		TOKEN_EQUAL:           {nil, nil, PREC_NONE},
+		TOKEN_EQUAL_EQUAL:     {nil, c.binary, PREC_EQUALITY},
+		TOKEN_GREATER:         {nil, c.binary, PREC_COMPARISON},
+		TOKEN_GREATER_EQUAL:   {nil, c.binary, PREC_COMPARISON},
+		TOKEN_LESS:            {nil, c.binary, PREC_COMPARISON},
+		TOKEN_LESS_EQUAL:      {nil, c.binary, PREC_COMPARISON},
+		TOKEN_IDENTIFIER:      {c.variable, nil, PREC_NONE},
+		TOKEN_STRING:          {c.str, nil, PREC_NONE},
+		TOKEN_INTERPOLATION:   {c.interpolation, nil, PREC_NONE},
+		TOKEN_NUMBER:          {c.number, nil, PREC_NONE},
+		TOKEN_AND:             {nil, c.and_, PREC_NONE},
+		TOKEN_CLASS:           {nil, nil, PREC_NONE},
+		TOKEN_ELSE:            {nil, nil, PREC_NONE},
+		TOKEN_FALSE:           {c.literal, nil, PREC_NONE},
+		TOKEN_FOR:             {nil, nil, PREC_NONE},
+		TOKEN_FUN:             {c.funExpression, nil, PREC_NONE},
+		TOKEN_IF:              {nil, nil, PREC_NONE},
+		TOKEN_NIL:             {c.literal, nil, PREC_NONE},
+		TOKEN_OR:              {nil, c.or_, PREC_NONE},
+		TOKEN_PRINT:           {nil, nil, PREC_NONE},
+		TOKEN_RETURN:          {nil, nil, PREC_NONE},
+		TOKEN_SUPER:           {c.super_, nil, PREC_NONE},
+		TOKEN_THIS:            {c.this_, nil, PREC_NONE},
+		TOKEN_TRUE:            {c.literal, nil, PREC_NONE},
+		TOKEN_VAR:             {nil, nil, PREC_NONE},
+		TOKEN_WHILE:           {nil, nil, PREC_NONE},
+		TOKEN_ERROR:           {nil, nil, PREC_NONE},
+		TOKEN_EOF:             {nil, nil, PREC_NONE},
+	}
+}
+
+func (c *Compiler) getRule(tok TokenType) ParseRule {
+	return c.rules[tok]
+}
+
+func syntheticToken(text string) Token {
+	return Token{Type: TOKEN_IDENTIFIER, Lexeme: text}
+}
+
+func identifiersEqual(a Token, b Token) bool {
+	return a.Lexeme == b.Lexeme
+}
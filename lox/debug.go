@@ -0,0 +1,249 @@
+package lox
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// DebugOptions controls the diagnostic output produced while compiling and
+// running. Everything is off by default, so normal runs only pay for a
+// boolean check.
+type DebugOptions struct {
+	PrintCode      bool
+	TraceExecution bool
+	Out            io.Writer
+}
+
+func (d DebugOptions) writer() io.Writer {
+	if d.Out == nil {
+		return os.Stdout
+	}
+	return d.Out
+}
+
+func DisassembleChunk(w io.Writer, c *Chunk, name string) {
+	fmt.Fprintf(w, "== %s ==\n", name)
+	for offset := 0; offset < c.Count(); {
+		offset = disassembleInstruction(w, c, offset)
+	}
+}
+
+// DisassembleFunction prints function's chunk followed by the chunks of every
+// function nested inside it.
+func DisassembleFunction(w io.Writer, function *ObjFunction) {
+	DisassembleChunk(w, &function.chunk, functionString(function))
+	for _, constant := range function.chunk.Constants.values {
+		if IsFunction(constant) {
+			fmt.Fprintln(w)
+			DisassembleFunction(w, AsFunc(constant))
+		}
+	}
+}
+
+func disassembleInstruction(w io.Writer, c *Chunk, offset int) int {
+	fmt.Fprintf(w, "%04d ", offset)
+	inst := c.Code[offset]
+	if offset > 0 && c.spans[offset].Line == c.spans[offset-1].Line {
+		fmt.Fprint(w, "   | ")
+	} else {
+		fmt.Fprintf(w, "%4d ", c.spans[offset].Line)
+	}
+
+	switch inst {
+	case OP_RETURN:
+		return simpleInstruction(w, "OP_RETURN", offset)
+	case OP_CONSTANT:
+		return constantInstruction(w, "OP_CONSTANT", offset, c)
+	case OP_NEGATE:
+		return simpleInstruction(w, "OP_NEGATE", offset)
+	case OP_DIVIDE:
+		return simpleInstruction(w, "OP_DIVIDE", offset)
+	case OP_ADD:
+		return simpleInstruction(w, "OP_ADD", offset)
+	case OP_FLOOR_DIVIDE:
+		return simpleInstruction(w, "OP_FLOOR_DIVIDE", offset)
+	case OP_MODULO:
+		return simpleInstruction(w, "OP_MODULO", offset)
+	case OP_POWER:
+		return simpleInstruction(w, "OP_POWER", offset)
+	case OP_BIT_AND:
+		return simpleInstruction(w, "OP_BIT_AND", offset)
+	case OP_BIT_OR:
+		return simpleInstruction(w, "OP_BIT_OR", offset)
+	case OP_BIT_XOR:
+		return simpleInstruction(w, "OP_BIT_XOR", offset)
+	case OP_BIT_NOT:
+		return simpleInstruction(w, "OP_BIT_NOT", offset)
+	case OP_SHIFT_LEFT:
+		return simpleInstruction(w, "OP_SHIFT_LEFT", offset)
+	case OP_SHIFT_RIGHT:
+		return simpleInstruction(w, "OP_SHIFT_RIGHT", offset)
+	case OP_MULTIPLY:
+		return simpleInstruction(w, "OP_MULTIPLY", offset)
+	case OP_SUBSTRACT:
+		return simpleInstruction(w, "OP_SUBSTRACT", offset)
+	case OP_NIL:
+		return simpleInstruction(w, "OP_NIL", offset)
+	case OP_TRUE:
+		return simpleInstruction(w, "OP_TRUE", offset)
+	case OP_FALSE:
+		return simpleInstruction(w, "OP_FALSE", offset)
+	case OP_DUP:
+		return simpleInstruction(w, "OP_DUP", offset)
+	case OP_POP:
+		return simpleInstruction(w, "OP_POP", offset)
+	case OP_DEFINE_GLOBAL:
+		return constantInstruction(w, "OP_DEFINE_GLOBAL", offset, c)
+	case OP_NOT:
+		return simpleInstruction(w, "OP_NOT", offset)
+	case OP_EQUAL:
+		return simpleInstruction(w, "OP_EQUAL", offset)
+	case OP_GREATER:
+		return simpleInstruction(w, "OP_GREATER", offset)
+	case OP_LESS:
+		return simpleInstruction(w, "OP_LESS", offset)
+	case OP_PRINT:
+		return simpleInstruction(w, "OP_PRINT", offset)
+	case OP_GET_GLOBAL:
+		return constantInstruction(w, "OP_GET_GLOBAL", offset, c)
+	case OP_SET_GLOBAL:
+		return constantInstruction(w, "OP_SET_GLOBAL", offset, c)
+	case OP_GET_LOCAL:
+		return byteInstruction(w, "OP_GET_LOCAL", offset, c)
+	case OP_SET_LOCAL:
+		return byteInstruction(w, "OP_SET_LOCAL", offset, c)
+	case OP_JUMP_IF_FALSE:
+		return jumpInstruction(w, "OP_JUMP_IF_FALSE", 1, offset, c)
+	case OP_JUMP:
+		return jumpInstruction(w, "OP_JUMP", 1, offset, c)
+	case OP_LOOP:
+		return jumpInstruction(w, "OP_LOOP", -1, offset, c)
+	case OP_CALL:
+		return byteInstruction(w, "OP_CALL", offset, c)
+	case OP_CLOSURE:
+		return closureInstruction(w, "OP_CLOSURE", offset, c)
+	case OP_GET_UPVALUE:
+		return byteInstruction(w, "OP_GET_UPVALUE", offset, c)
+	case OP_SET_UPVALUE:
+		return byteInstruction(w, "OP_SET_UPVALUE", offset, c)
+	case OP_CLOSE_UPVALUE:
+		return simpleInstruction(w, "OP_CLOSE_UPVALUE", offset)
+	case OP_CLASS:
+		return constantInstruction(w, "OP_CLASS", offset, c)
+	case OP_GET_PROPERTY:
+		return constantInstruction(w, "OP_GET_PROPERTY", offset, c)
+	case OP_SET_PROPERTY:
+		return constantInstruction(w, "OP_SET_PROPERTY", offset, c)
+	case OP_METHOD:
+		return constantInstruction(w, "OP_METHOD", offset, c)
+	case OP_INHERIT:
+		return simpleInstruction(w, "OP_INHERIT", offset)
+	case OP_GET_SUPER:
+		return constantInstruction(w, "OP_GET_SUPER", offset, c)
+	case OP_BUILD_LIST:
+		return byteInstruction(w, "OP_BUILD_LIST", offset, c)
+	case OP_BUILD_MAP:
+		return byteInstruction(w, "OP_BUILD_MAP", offset, c)
+	case OP_DEFINE_CONST:
+		return constantInstruction(w, "OP_DEFINE_CONST", offset, c)
+	case OP_CALL_NAMED:
+		return callNamedInstruction(w, offset, c)
+	case OP_SKIP_DEFAULT:
+		return skipDefaultInstruction(w, offset, c)
+	case OP_IMPORT:
+		return constantInstruction(w, "OP_IMPORT", offset, c)
+	case OP_IMPORT_FROM:
+		return constantInstruction(w, "OP_IMPORT_FROM", offset, c)
+	case OP_EXPORT:
+		return constantInstruction(w, "OP_EXPORT", offset, c)
+	case OP_TRY:
+		return jumpInstruction(w, "OP_TRY", 1, offset, c)
+	case OP_END_TRY:
+		return simpleInstruction(w, "OP_END_TRY", offset)
+	case OP_THROW:
+		return simpleInstruction(w, "OP_THROW", offset)
+	case OP_SAVE_THROWN:
+		return simpleInstruction(w, "OP_SAVE_THROWN", offset)
+	case OP_POPN:
+		return byteInstruction(w, "OP_POPN", offset, c)
+	case OP_TO_STRING:
+		return simpleInstruction(w, "OP_TO_STRING", offset)
+	case OP_INDEX_GET:
+		return simpleInstruction(w, "OP_INDEX_GET", offset)
+	case OP_INDEX_SET:
+		return simpleInstruction(w, "OP_INDEX_SET", offset)
+	default:
+		fmt.Fprintf(w, "Unknown opcode %d\n", inst)
+		return offset + 1
+	}
+
+}
+
+func simpleInstruction(w io.Writer, name string, offset int) int {
+	fmt.Fprintf(w, "%s\n", name)
+	return offset + 1
+}
+
+func byteInstruction(w io.Writer, name string, offset int, c *Chunk) int {
+	slot := c.Code[offset+1]
+	fmt.Fprintf(w, "%-16s %4d\n", name, slot)
+	return offset + 2
+}
+
+func constantInstruction(w io.Writer, name string, offset int, c *Chunk) int {
+	constantIdx := c.Code[offset+1]
+	fmt.Fprintf(w, "%-16s %4d '", name, constantIdx)
+	fmt.Fprint(w, c.Constants.values[constantIdx].String())
+
+	fmt.Fprintf(w, "'\n")
+	return offset + 2
+}
+
+func closureInstruction(w io.Writer, name string, offset int, c *Chunk) int {
+	constantIdx := c.Code[offset+1]
+	offset += 2
+	fmt.Fprintf(w, "%-16s %4d ", name, constantIdx)
+	fmt.Fprint(w, c.Constants.values[constantIdx].String())
+	fmt.Fprintln(w)
+
+	function := AsFunc(c.Constants.values[constantIdx])
+	for j := 0; j < function.upvalueCount; j++ {
+		isLocal := c.Code[offset]
+		index := c.Code[offset+1]
+		kind := "upvalue"
+		if isLocal == 1 {
+			kind = "local"
+		}
+		fmt.Fprintf(w, "%04d      |                     %s %d\n", offset, kind, index)
+		offset += 2
+	}
+	return offset
+}
+
+func jumpInstruction(w io.Writer, name string, sign int, offset int, c *Chunk) int {
+	jump := (uint16(c.Code[offset+1]) << 8)
+	jump |= uint16(c.Code[offset+2])
+	fmt.Fprintf(w, "%-16s %4d -> %d\n", name, offset,
+		offset+3+sign*(int(jump)))
+	return offset + 3
+}
+
+func callNamedInstruction(w io.Writer, offset int, c *Chunk) int {
+	argCount := c.Code[offset+1]
+	nameCount := int(c.Code[offset+2])
+	names := make([]string, nameCount)
+	for i := range names {
+		names[i] = c.Constants.values[c.Code[offset+3+i]].String()
+	}
+	fmt.Fprintf(w, "%-16s %4d (%s)\n", "OP_CALL_NAMED", argCount, strings.Join(names, ", "))
+	return offset + 3 + nameCount
+}
+
+func skipDefaultInstruction(w io.Writer, offset int, c *Chunk) int {
+	slot := c.Code[offset+1]
+	jump := int(c.Code[offset+2])<<8 | int(c.Code[offset+3])
+	fmt.Fprintf(w, "%-16s %4d -> %d\n", "OP_SKIP_DEFAULT", slot, offset+4+jump)
+	return offset + 4
+}
@@ -0,0 +1,58 @@
+package lox_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/armadi1809/cloximp/lox"
+)
+
+func TestDebugOutput(t *testing.T) {
+	const source = "print 1 + 2;"
+	tests := []struct {
+		name      string
+		debug     lox.DebugOptions
+		want      []string
+		wantEmpty bool
+	}{
+		{
+			name:      "off",
+			wantEmpty: true,
+		},
+		{
+			name:  "dump bytecode",
+			debug: lox.DebugOptions{PrintCode: true},
+			want:  []string{"== <script> ==\n0000    1 OP_CONSTANT         0 '1'\n", "0004    | OP_ADD\n"},
+		},
+		{
+			name:  "trace",
+			debug: lox.DebugOptions{TraceExecution: true},
+			want:  []string{"          [ <script> ][ 1 ][ 2 ]\n0004    | OP_ADD\n          [ <script> ][ 3 ]\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, trace strings.Builder
+			tt.debug.Out = &trace
+			vm := lox.NewVM(lox.WithOutput(&out), lox.WithDebug(tt.debug))
+			if result := vm.Interpret(source); result != lox.INTERPRET_OK {
+				t.Fatalf("Interpret = %v", result)
+			}
+			if got := out.String(); got != "3\n" {
+				t.Errorf("program output = %q, want %q", got, "3\n")
+			}
+			got := trace.String()
+			if tt.wantEmpty && got != "" {
+				t.Errorf("debug output = %q, want none", got)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("debug output:\n%s\nwant it to contain:\n%s", got, want)
+				}
+			}
+			if tt.debug.PrintCode && strings.Contains(got, "[ <script> ]") {
+				t.Error("dumping bytecode also traced execution")
+			}
+		})
+	}
+}
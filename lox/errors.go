@@ -0,0 +1,169 @@
+package lox
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrCompile = errors.New("compile error")
+	ErrRuntime = errors.New("runtime error")
+)
+
+// Diagnostic is a single problem reported by the compiler.
+type Diagnostic struct {
+	Span
+	// Source names the code the error is in, and Text is the full line
+	// containing the error, without its line terminator.
+	Source string
+	Text   string
+	// Lexeme is the text of the offending token. It is empty for errors
+	// reported by the scanner and for errors at the end of the source.
+	Lexeme  string
+	Message string
+	Note    string
+	AtEnd   bool
+}
+
+// String renders the diagnostic in a rustc-like layout: the message, its
+// location, the source line with the token underlined and an optional note.
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "error: %s", d.Message)
+	writeSnippet(&b, d.Source, d.Text, d.Span)
+	if d.Note != "" {
+		fmt.Fprintf(&b, "\n%s = note: %s", strings.Repeat(" ", gutterWidth(d.Line)), d.Note)
+	}
+	return b.String()
+}
+
+// CompileError is returned when source fails to compile. It holds every
+// diagnostic found, up to the VM's error limit; Truncated reports whether
+// more were dropped. It matches ErrCompile with errors.Is.
+type CompileError struct {
+	Diagnostics []Diagnostic
+	Truncated   bool
+}
+
+func (e *CompileError) Error() string {
+	parts := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		parts[i] = d.String()
+	}
+	if e.Truncated {
+		parts = append(parts, "error: Too many errors, stopping.")
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func (e *CompileError) Is(target error) bool {
+	return target == ErrCompile
+}
+
+// StackFrame is one call that was active when a runtime error occurred. Span
+// locates the instruction that was executing; Source and Text are set as in
+// Diagnostic when the source is available.
+type StackFrame struct {
+	// Function is the function's name, or "script" for top-level code.
+	// InModule is set for the top-level code of an imported module.
+	Function string
+	InModule bool
+	Span
+	Source string
+	Text   string
+}
+
+// RuntimeError is returned when a program fails while running. Trace lists
+// the active calls, innermost first. It matches ErrRuntime with errors.Is.
+type RuntimeError struct {
+	Message string
+	Trace   []StackFrame
+}
+
+func (e *RuntimeError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "error: %s", e.Message)
+	if len(e.Trace) == 0 {
+		return b.String()
+	}
+	top := e.Trace[0]
+	writeSnippet(&b, top.Source, top.Text, top.Span)
+	b.WriteString("\n")
+	b.WriteString(traceString(e.Trace))
+	return b.String()
+}
+
+// traceString renders one "[line N] in f()" line per frame, innermost first.
+// An imported module's top-level code is named by its source.
+func traceString(trace []StackFrame) string {
+	lines := make([]string, len(trace))
+	for i, frame := range trace {
+		if frame.InModule {
+			lines[i] = fmt.Sprintf("[line %d] in %s", frame.Line, frame.Source)
+		} else if frame.Function == "script" {
+			lines[i] = fmt.Sprintf("[line %d] in script", frame.Line)
+		} else {
+			lines[i] = fmt.Sprintf("[line %d] in %s()", frame.Line, frame.Function)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e *RuntimeError) Is(target error) bool {
+	return target == ErrRuntime
+}
+
+// writeSnippet writes the location line and, when the source text is known,
+// the quoted line with a caret underline beneath the span.
+func writeSnippet(b *strings.Builder, source, text string, span Span) {
+	pad := strings.Repeat(" ", gutterWidth(span.Line))
+	fmt.Fprintf(b, "\n%s--> %s:%d:%d", pad, source, span.Line, span.Column)
+	if text == "" {
+		return
+	}
+
+	prefix := text
+	if span.Column-1 < utf8.RuneCountInString(text) {
+		prefix = string([]rune(text)[:span.Column-1])
+	}
+	// Keep tabs in the indentation so the caret lines up with the quoted
+	// line in any tab width.
+	indent := []rune(prefix)
+	for i, r := range indent {
+		if r != '\t' {
+			indent[i] = ' '
+		}
+	}
+
+	// The span's length is in bytes; underline it one caret per character,
+	// stopping at the end of the line.
+	start := len(prefix)
+	end := min(start+span.Length, len(text))
+	width := max(utf8.RuneCountInString(text[start:end]), 1)
+
+	fmt.Fprintf(b, "\n%s |", pad)
+	fmt.Fprintf(b, "\n%d | %s", span.Line, text)
+	fmt.Fprintf(b, "\n%s | %s%s", pad, string(indent), strings.Repeat("^", width))
+}
+
+func gutterWidth(line int) int {
+	return len(strconv.Itoa(line))
+}
+
+// lineText returns the line of text containing the byte offset.
+func lineText(text string, offset int) string {
+	if offset > len(text) {
+		return ""
+	}
+	start := strings.LastIndexByte(text[:offset], '\n') + 1
+	end := strings.IndexByte(text[offset:], '\n')
+	if end == -1 {
+		end = len(text)
+	} else {
+		end += offset
+	}
+	return strings.TrimRight(text[start:end], "\r")
+}
@@ -0,0 +1,306 @@
+package lox_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/armadi1809/cloximp/lox"
+)
+
+func TestSnippetCarets(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "ascii token",
+			source: "var 12;",
+			want: "error: Expected variable name\n" +
+				" --> test.jlox:1:5\n" +
+				"  |\n" +
+				"1 | var 12;\n" +
+				"  |     ^^",
+		},
+		{
+			name:   "multibyte token",
+			source: `var "héé";`,
+			want: "error: Expected variable name\n" +
+				" --> test.jlox:1:5\n" +
+				"  |\n" +
+				`1 | var "héé";` + "\n" +
+				"  |     ^^^^^",
+		},
+		{
+			name:   "after multibyte text",
+			source: `var s = "é" 12;`,
+			want: "error: Expect ';' after variable declaration.\n" +
+				" --> test.jlox:1:13\n" +
+				"  |\n" +
+				`1 | var s = "é" 12;` + "\n" +
+				"  |             ^^",
+		},
+		{
+			name:   "tab indentation",
+			source: "{\n\tvar = 1;\n}",
+			want: "error: Expected variable name\n" +
+				" --> test.jlox:2:6\n" +
+				"  |\n" +
+				"2 | \tvar = 1;\n" +
+				"  | \t    ^",
+		},
+		{
+			name:   "bad escape",
+			source: `print "é\q";`,
+			want: "error: Invalid escape sequence.\n" +
+				" --> test.jlox:1:9\n" +
+				"  |\n" +
+				`1 | print "é\q";` + "\n" +
+				"  |         ^",
+		},
+		{
+			name:   "code point out of range",
+			source: "print \"a\nb\\u{110000}\";",
+			want: "error: Invalid unicode code point.\n" +
+				" --> test.jlox:2:2\n" +
+				"  |\n" +
+				`2 | b\u{110000}";` + "\n" +
+				"  |  ^",
+		},
+		{
+			name:   "empty unicode escape",
+			source: `print "x\u{}";`,
+			want: "error: Invalid unicode escape sequence.\n" +
+				" --> test.jlox:1:9\n" +
+				"  |\n" +
+				`1 | print "x\u{}";` + "\n" +
+				"  |         ^",
+		},
+		{
+			name:   "end of input",
+			source: "print 1 +",
+			want: "error: Expect expression.\n" +
+				" --> test.jlox:1:10\n" +
+				"  |\n" +
+				"1 | print 1 +\n" +
+				"  |          ^",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := lox.NewVM().CompileNamed("test.jlox", tt.source)
+			if err == nil {
+				t.Fatal("compiled without error")
+			}
+			if got := err.Error(); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuntimeErrorSnippet(t *testing.T) {
+	vm := lox.NewVM()
+	program, err := vm.CompileNamed("test.jlox", "var s = \"é\";\nprint s + nil;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = vm.Run(program)
+	var runtimeErr *lox.RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("got %v, want a *RuntimeError", err)
+	}
+	want := " --> test.jlox:2:9\n" +
+		"  |\n" +
+		"2 | print s + nil;\n" +
+		"  |         ^"
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("got:\n%s\nwant it to contain:\n%s", err, want)
+	}
+}
+
+func TestCompileErrorShape(t *testing.T) {
+	_, err := lox.NewVM().CompileNamed("shape.jlox", "var a = 1;\nvar b = a +;")
+	if !errors.Is(err, lox.ErrCompile) || errors.Is(err, lox.ErrRuntime) {
+		t.Fatalf("got %v, want an error matching only ErrCompile", err)
+	}
+	var compileErr *lox.CompileError
+	if !errors.As(err, &compileErr) {
+		t.Fatalf("got %T, want *CompileError", err)
+	}
+	if len(compileErr.Diagnostics) != 1 || compileErr.Truncated {
+		t.Fatalf("got %d diagnostics, truncated %v; want 1, false", len(compileErr.Diagnostics), compileErr.Truncated)
+	}
+	got := compileErr.Diagnostics[0]
+	want := lox.Diagnostic{
+		Span:    lox.Span{Line: 2, Column: 12, Offset: 22, Length: 1},
+		Source:  "shape.jlox",
+		Text:    "var b = a +;",
+		Lexeme:  ";",
+		Message: "Expect expression.",
+	}
+	if got != want {
+		t.Errorf("diagnostic = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestRuntimeErrorShape(t *testing.T) {
+	source := "fun inner() {\n  return nil + 1;\n}\nfun outer() {\n  return inner();\n}\nouter();"
+	vm := lox.NewVM()
+	program, err := vm.CompileNamed("shape.jlox", source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = vm.Run(program)
+	if !errors.Is(err, lox.ErrRuntime) || errors.Is(err, lox.ErrCompile) {
+		t.Fatalf("got %v, want an error matching only ErrRuntime", err)
+	}
+	var runtimeErr *lox.RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("got %T, want *RuntimeError", err)
+	}
+	if want := "Operands must be two numbers or two strings"; runtimeErr.Message != want {
+		t.Errorf("message = %q, want %q", runtimeErr.Message, want)
+	}
+
+	type frame struct {
+		function string
+		line     int
+	}
+	want := []frame{{"inner", 2}, {"outer", 5}, {"script", 7}}
+	var got []frame
+	for _, f := range runtimeErr.Trace {
+		got = append(got, frame{f.Function, f.Line})
+	}
+	if len(got) != len(want) {
+		t.Fatalf("trace = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("frame %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if top := runtimeErr.Trace[0]; top.Source != "shape.jlox" || top.Text != "  return nil + 1;" {
+		t.Errorf("top frame source = %q, text = %q", top.Source, top.Text)
+	}
+	if !strings.HasSuffix(err.Error(), "[line 2] in inner()\n[line 5] in outer()\n[line 7] in script") {
+		t.Errorf("error text:\n%s\nwant it to end with the stack trace", err)
+	}
+}
+
+func TestCollectsEveryError(t *testing.T) {
+	source := strings.Join([]string{
+		"var = 1;",
+		"print 1 +;",
+		"fun f() {",
+		"  return ) ;",
+		"}",
+		"class { }",
+		"var ok = 2;",
+		"if (ok print ok;",
+	}, "\n")
+	wantLines := []int{1, 2, 4, 6, 8}
+
+	tests := []struct {
+		name          string
+		opts          []lox.Option
+		wantCount     int
+		wantTruncated bool
+	}{
+		{"default limit", nil, 5, false},
+		{"no limit", []lox.Option{lox.WithMaxErrors(0)}, 5, false},
+		{"limit reached", []lox.Option{lox.WithMaxErrors(5)}, 5, false},
+		{"limit exceeded", []lox.Option{lox.WithMaxErrors(2)}, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := lox.NewVM(tt.opts...).Compile(source)
+			var compileErr *lox.CompileError
+			if !errors.As(err, &compileErr) {
+				t.Fatalf("got %v, want a *CompileError", err)
+			}
+			if len(compileErr.Diagnostics) != tt.wantCount || compileErr.Truncated != tt.wantTruncated {
+				t.Fatalf("got %d diagnostics, truncated %v; want %d, %v:\n%s",
+					len(compileErr.Diagnostics), compileErr.Truncated, tt.wantCount, tt.wantTruncated, err)
+			}
+			for i, d := range compileErr.Diagnostics {
+				if d.Line != wantLines[i] {
+					t.Errorf("diagnostic %d is on line %d, want %d", i, d.Line, wantLines[i])
+				}
+			}
+			stopped := strings.HasSuffix(err.Error(), "error: Too many errors, stopping.")
+			if stopped != tt.wantTruncated {
+				t.Errorf("error text ends with the stop message: %v, want %v", stopped, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestCallErrorsReportedOnce(t *testing.T) {
+	tests := []struct {
+		call string
+		want string
+	}{
+		{"f(a: 1, a: 2);", "Argument 'a' is passed more than once."},
+		{"f(a: 1, a: 2, a: 3);", "Argument 'a' is passed more than once."},
+		{"f(1, a: 2);", "f() got more than one value for 'a'."},
+		{"f(1, 2, 3);", "Expected 2 arguments but got 3."},
+		{"f(b: 1);", "f() is missing argument 'a'."},
+	}
+	for _, tt := range tests {
+		_, err := lox.NewVM().Compile("fun f(a, b) {}\n" + tt.call)
+		var compileErr *lox.CompileError
+		if !errors.As(err, &compileErr) {
+			t.Errorf("%s: got %v, want a *CompileError", tt.call, err)
+			continue
+		}
+		var messages []string
+		for _, d := range compileErr.Diagnostics {
+			messages = append(messages, d.Message)
+		}
+		if len(messages) != 1 || messages[0] != tt.want {
+			t.Errorf("%s: got %q, want [%q]", tt.call, messages, tt.want)
+		}
+	}
+}
+
+func TestTraceNamesModules(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.jlox": "import \"b\";",
+		"b.jlox": "\nimport \"a\";",
+	}
+	for name, source := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	vm := lox.NewVM()
+	path := filepath.Join(dir, "main.jlox")
+	program, err := vm.CompileNamed(path, "var x = 1;\nimport \"a\";")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = vm.Run(program)
+	var runtimeErr *lox.RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("got %v, want a *RuntimeError", err)
+	}
+	want := fmt.Sprintf("[line 2] in %s\n[line 1] in %s\n[line 2] in script",
+		runtimeErr.Trace[0].Source, runtimeErr.Trace[1].Source)
+	if !strings.HasSuffix(err.Error(), want) {
+		t.Errorf("error text:\n%s\nwant it to end with:\n%s", err, want)
+	}
+	for i, wantBase := range []string{"b.jlox", "a.jlox"} {
+		if frame := runtimeErr.Trace[i]; !frame.InModule || filepath.Base(frame.Source) != wantBase {
+			t.Errorf("frame %d = %+v, want the top level of %s", i, frame, wantBase)
+		}
+	}
+	if runtimeErr.Trace[2].InModule {
+		t.Error("the main script's frame is marked as a module")
+	}
+}
@@ -0,0 +1,65 @@
+package lox_test
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/armadi1809/cloximp/lox"
+)
+
+var update = flag.Bool("update", false, "rewrite the expected output of the scripts in tests/")
+
+// TestScripts runs each tests/*.jlox script and compares what it prints,
+// followed by any error, with the script's .out file. Scripts run from the
+// repository root, as 'jlox run tests/name.jlox' would.
+func TestScripts(t *testing.T) {
+	t.Chdir("..")
+	scripts, err := filepath.Glob(filepath.Join("tests", "*.jlox"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scripts) == 0 {
+		t.Fatal("no scripts found")
+	}
+	for _, script := range scripts {
+		name := strings.TrimSuffix(filepath.Base(script), ".jlox")
+		t.Run(name, func(t *testing.T) {
+			got := runScript(t, script)
+			golden := strings.TrimSuffix(script, ".jlox") + ".out"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if got != string(want) {
+				t.Errorf("output differs from %s\ngot:\n%s\nwant:\n%s", golden, got, want)
+			}
+		})
+	}
+}
+
+func runScript(t *testing.T, path string) string {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	vm := lox.NewVM(lox.WithOutput(&out), lox.WithErrorOutput(&out))
+	program, err := vm.CompileNamed(path, string(source))
+	if err == nil {
+		_, err = vm.Run(program)
+	}
+	if err != nil {
+		fmt.Fprintln(&out, err)
+	}
+	return out.String()
+}
@@ -0,0 +1,149 @@
+package lox
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// listIndex checks that index is a whole number addressing one of length
+// items and returns its position. Negative indices count from the end.
+func listIndex(index Value, length int) (int, error) {
+	i, err := integerArg(index, "Index")
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 {
+		i += length
+	}
+	if i < 0 || i >= length {
+		return 0, fmt.Errorf("Index %s out of range for length %d.", index.String(), length)
+	}
+	return i, nil
+}
+
+// sliceBounds resolves start and end the way slice() does: negative values
+// count from the end and out-of-range values are clamped.
+func sliceBounds(start, end, length int) (int, int) {
+	if start < 0 {
+		start += length
+	}
+	if end < 0 {
+		end += length
+	}
+	start = min(max(start, 0), length)
+	end = min(max(end, start), length)
+	return start, end
+}
+
+func integerArg(val Value, what string) (int, error) {
+	if !isNumber(val) {
+		return 0, fmt.Errorf("%s must be a number.", what)
+	}
+	if isInt(val) {
+		return int(val.AsInt()), nil
+	}
+	n := val.AsNumber()
+	if n != math.Trunc(n) || n >= 1<<63 || n < -(1<<63) {
+		return 0, fmt.Errorf("%s must be a whole number.", what)
+	}
+	return int(n), nil
+}
+
+func listArg(args []Value, i int, name string) (*ObjList, error) {
+	if !IsList(args[i]) {
+		return nil, fmt.Errorf("%s() expects a list but got %s.", name, typeName(args[i]))
+	}
+	return AsList(args[i]), nil
+}
+
+func pushNative(args []Value) (Value, error) {
+	list, err := listArg(args, 0, "push")
+	if err != nil {
+		return nil, err
+	}
+	list.items = append(list.items, args[1])
+	return NilVal{}, nil
+}
+
+func popNative(args []Value) (Value, error) {
+	list, err := listArg(args, 0, "pop")
+	if err != nil {
+		return nil, err
+	}
+	if len(list.items) == 0 {
+		return nil, errors.New("Can't pop from an empty list.")
+	}
+	last := list.items[len(list.items)-1]
+	list.items = list.items[:len(list.items)-1]
+	return last, nil
+}
+
+func insertNative(args []Value) (Value, error) {
+	list, err := listArg(args, 0, "insert")
+	if err != nil {
+		return nil, err
+	}
+	// Inserting at len(list) appends, so the valid range is one wider than
+	// for indexing.
+	i, err := listIndex(args[1], len(list.items)+1)
+	if err != nil {
+		return nil, err
+	}
+	list.items = append(list.items, nil)
+	copy(list.items[i+1:], list.items[i:])
+	list.items[i] = args[2]
+	return NilVal{}, nil
+}
+
+func removeNative(args []Value) (Value, error) {
+	list, err := listArg(args, 0, "remove")
+	if err != nil {
+		return nil, err
+	}
+	i, err := listIndex(args[1], len(list.items))
+	if err != nil {
+		return nil, err
+	}
+	removed := list.items[i]
+	list.items = append(list.items[:i], list.items[i+1:]...)
+	return removed, nil
+}
+
+// sliceNative returns a copy of part of a list or string:
+// slice(xs, start) or slice(xs, start, end).
+func sliceNative(args []Value) (Value, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, fmt.Errorf("Expected 2 or 3 arguments but got %d.", len(args))
+	}
+
+	var length int
+	switch {
+	case IsList(args[0]):
+		length = len(AsList(args[0]).items)
+	case IsString(args[0]):
+		length = len([]rune(AsLiteralString(args[0])))
+	default:
+		return nil, fmt.Errorf("slice() expects a list or string but got %s.", typeName(args[0]))
+	}
+
+	start, err := integerArg(args[1], "Slice start")
+	if err != nil {
+		return nil, err
+	}
+	end := length
+	if len(args) == 3 {
+		if end, err = integerArg(args[2], "Slice end"); err != nil {
+			return nil, err
+		}
+	}
+	start, end = sliceBounds(start, end, length)
+
+	if IsString(args[0]) {
+		chars := []rune(AsLiteralString(args[0]))
+		return ObjVal{Object: CreateStringObj(string(chars[start:end]))}, nil
+	}
+	items := make([]Value, end-start)
+	copy(items, AsList(args[0]).items[start:end])
+	return ObjVal{Object: NewList(items)}, nil
+}
@@ -0,0 +1,115 @@
+package lox
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// checkKey reports an error if key can't be used to index a map.
+func checkKey(key Value) error {
+	switch {
+	case isNil(key), isBool(key), IsString(key):
+		return nil
+	case isInt(key):
+		return nil
+	case isFloat(key):
+		if math.IsNaN(key.AsNumber()) {
+			return errors.New("Can't use NaN as a map key.")
+		}
+		return nil
+	}
+	return fmt.Errorf("Can't use %s as a map key.", typeName(key))
+}
+
+// mapKey gives whole floats the same key as the equal integer, so that
+// m[1] and m[1.0] are the same entry, as 1 == 1.0.
+func mapKey(key Value) Value {
+	if isFloat(key) {
+		if n, err := floatToInt(key.AsNumber()); err == nil && float64(n) == key.AsNumber() {
+			return IntVal(n)
+		}
+	}
+	return key
+}
+
+func (m *ObjMap) get(key Value) (Value, bool) {
+	key = mapKey(key)
+	val, ok := m.entries[key]
+	return val, ok
+}
+
+func (m *ObjMap) set(key Value, val Value) {
+	key = mapKey(key)
+	if _, ok := m.entries[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.entries[key] = val
+}
+
+func (m *ObjMap) delete(key Value) bool {
+	key = mapKey(key)
+	if _, ok := m.entries[key]; !ok {
+		return false
+	}
+	delete(m.entries, key)
+	for i, k := range m.keys {
+		if k == key {
+			m.keys = append(m.keys[:i], m.keys[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func mapArg(args []Value, i int, name string) (*ObjMap, error) {
+	if !IsMap(args[i]) {
+		return nil, fmt.Errorf("%s() expects a map but got %s.", name, typeName(args[i]))
+	}
+	return AsMap(args[i]), nil
+}
+
+func hasNative(args []Value) (Value, error) {
+	m, err := mapArg(args, 0, "has")
+	if err != nil {
+		return nil, err
+	}
+	if err := checkKey(args[1]); err != nil {
+		return nil, err
+	}
+	_, ok := m.get(args[1])
+	return BoolVal(ok), nil
+}
+
+func deleteNative(args []Value) (Value, error) {
+	m, err := mapArg(args, 0, "delete")
+	if err != nil {
+		return nil, err
+	}
+	if err := checkKey(args[1]); err != nil {
+		return nil, err
+	}
+	return BoolVal(m.delete(args[1])), nil
+}
+
+func keysNative(args []Value) (Value, error) {
+	m, err := mapArg(args, 0, "keys")
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]Value, len(m.keys))
+	copy(keys, m.keys)
+	return ObjVal{Object: NewList(keys)}, nil
+}
+
+func valuesNative(args []Value) (Value, error) {
+	m, err := mapArg(args, 0, "values")
+	if err != nil {
+		return nil, err
+	}
+	values := make([]Value, len(m.keys))
+	for i, key := range m.keys {
+		values[i] = m.entries[key]
+	}
+	return ObjVal{Object: NewList(values)}, nil
+}
@@ -0,0 +1,137 @@
+package lox
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// importModule pushes the module at path and then the value its top-level
+// code returned. A module seen for the first time is compiled and cached,
+// and a frame is pushed to run it, so its return value fills the second
+// slot once it finishes.
+func (vm *VM) importModule(path ObjString) bool {
+	importer := ""
+	if source := vm.getCurrentFrame().closure.function.source; source != nil {
+		importer = source.Name
+	}
+	file, err := resolveModule(path.Characters, importer)
+	if err != nil {
+		vm.runtimeError("%s", err.Error())
+		return false
+	}
+	key := canonicalPath(file)
+
+	if module, ok := vm.modules[key]; ok {
+		if !module.loaded {
+			if cycle := vm.importCycle(module); cycle != "" {
+				vm.runtimeError("Import cycle: %s.", cycle)
+			} else {
+				vm.runtimeError("Module '%s' failed to load.", path.Characters)
+			}
+			return false
+		}
+		vm.pushStack(ObjVal{Object: module})
+		vm.pushStack(NilVal{})
+		return true
+	}
+
+	text, err := os.ReadFile(file)
+	if err != nil {
+		vm.runtimeError("Can't read module '%s': %s.", path.Characters, err)
+		return false
+	}
+	function, err := vm.newCompiler().compile(&SourceFile{Name: file, Text: string(text)})
+	if err != nil {
+		vm.runtimeError("Can't compile module '%s':\n%s", path.Characters, err)
+		return false
+	}
+	module := NewModule(file)
+	module.function = function
+	vm.modules[key] = module
+
+	closure := NewClosure(function)
+	closure.module = module
+	vm.pushStack(ObjVal{Object: module})
+	vm.pushStack(ObjVal{Object: closure})
+	return vm.call(closure, 0)
+}
+
+// resolveModule finds the file for an import, first relative to the
+// importing file and then in each directory listed in LOX_PATH. The .jlox
+// extension may be left off.
+func resolveModule(path, importer string) (string, error) {
+	dirs := []string{""}
+	if !filepath.IsAbs(path) {
+		dir := "."
+		if importer != "" && !strings.HasPrefix(importer, "<") {
+			dir = filepath.Dir(importer)
+		}
+		dirs = append([]string{dir}, filepath.SplitList(os.Getenv("LOX_PATH"))...)
+	}
+	candidates := []string{path}
+	if filepath.Ext(path) == "" {
+		candidates = append(candidates, path+".jlox")
+	}
+	for _, dir := range dirs {
+		for _, candidate := range candidates {
+			file := filepath.Join(dir, candidate)
+			if info, err := os.Stat(file); err == nil && !info.IsDir() {
+				return file, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("Can't find module '%s'.", path)
+}
+
+// canonicalPath identifies a module file however it was reached.
+func canonicalPath(file string) string {
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	if real, err := filepath.EvalSymlinks(file); err == nil {
+		file = real
+	}
+	return file
+}
+
+// importCycle describes the chain of imports leading back to module if it
+// is still running further down the call stack.
+func (vm *VM) importCycle(module *ObjModule) string {
+	var chain []string
+	for _, frame := range vm.frames {
+		running := frame.closure.module
+		if frame.closure.function != running.function {
+			continue
+		}
+		if running == module || len(chain) > 0 {
+			chain = append(chain, running.name)
+		}
+	}
+	if len(chain) == 0 {
+		return ""
+	}
+	return strings.Join(append(chain, module.name), " -> ")
+}
+
+// export returns the value of an exported global.
+func (module *ObjModule) export(name ObjString) (Value, bool) {
+	if !module.exports[name] {
+		return nil, false
+	}
+	value, ok := module.globals[name]
+	return value, ok
+}
+
+func (vm *VM) moduleProperty(name ObjString) bool {
+	module := AsModule(vm.peek(0))
+	value, ok := module.export(name)
+	if !ok {
+		vm.runtimeError("Module '%s' does not export '%s'.", module.name, name.Characters)
+		return false
+	}
+	vm.popStack()
+	vm.pushStack(value)
+	return true
+}
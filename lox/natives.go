@@ -0,0 +1,164 @@
+package lox
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefineNative registers a Go function as a builtin callable from Lox in
+// every module. An arity of -1 accepts any number of arguments.
+func (vm *VM) DefineNative(name string, arity int, function NativeFn) {
+	vm.builtins[CreateStringObj(name)] = ObjVal{Object: NewNative(name, arity, function)}
+}
+
+func (vm *VM) defineNatives() {
+	vm.DefineNative("clock", 0, clockNative)
+	vm.DefineNative("typeof", 1, typeofNative)
+	vm.DefineNative("str", 1, strNative)
+	vm.DefineNative("num", 1, numNative)
+	vm.DefineNative("int", 1, intNative)
+	vm.DefineNative("float", 1, floatNative)
+	vm.DefineNative("len", 1, lenNative)
+	vm.DefineNative("push", 2, pushNative)
+	vm.DefineNative("pop", 1, popNative)
+	vm.DefineNative("insert", 3, insertNative)
+	vm.DefineNative("remove", 2, removeNative)
+	vm.DefineNative("slice", -1, sliceNative)
+	vm.DefineNative("has", 2, hasNative)
+	vm.DefineNative("delete", 2, deleteNative)
+	vm.DefineNative("keys", 1, keysNative)
+	vm.DefineNative("values", 1, valuesNative)
+	vm.DefineNative("Error", 1, vm.errorNative)
+	vm.DefineNative("argc", 0, vm.argcNative)
+	vm.DefineNative("argv", 1, vm.argvNative)
+}
+
+func clockNative(args []Value) (Value, error) {
+	return NumberVal(float64(time.Now().UnixNano()) / float64(time.Second)), nil
+}
+
+func typeofNative(args []Value) (Value, error) {
+	return ObjVal{Object: CreateStringObj(typeName(args[0]))}, nil
+}
+
+func strNative(args []Value) (Value, error) {
+	if IsString(args[0]) {
+		return args[0], nil
+	}
+	return ObjVal{Object: CreateStringObj(args[0].String())}, nil
+}
+
+func numNative(args []Value) (Value, error) {
+	switch {
+	case isNumber(args[0]):
+		return args[0], nil
+	case IsString(args[0]):
+		literal := strings.TrimSpace(AsLiteralString(args[0]))
+		if val, err := strconv.ParseInt(literal, 10, 64); err == nil {
+			return IntVal(val), nil
+		}
+		val, err := strconv.ParseFloat(literal, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Can't convert '%s' to a number.", literal)
+		}
+		return NumberVal(val), nil
+	case isBool(args[0]):
+		if args[0].AsBoolean() {
+			return IntVal(1), nil
+		}
+		return IntVal(0), nil
+	}
+	return nil, fmt.Errorf("Can't convert %s to a number.", typeName(args[0]))
+}
+
+// intNative converts a number, or a string holding one, to an integer,
+// truncating toward zero.
+func intNative(args []Value) (Value, error) {
+	val, err := numNative(args)
+	if err != nil || isInt(val) {
+		return val, err
+	}
+	n, err := floatToInt(val.AsNumber())
+	if err != nil {
+		return nil, fmt.Errorf("Can't convert %s to an integer.", val)
+	}
+	return IntVal(n), nil
+}
+
+func floatNative(args []Value) (Value, error) {
+	val, err := numNative(args)
+	if err != nil {
+		return nil, err
+	}
+	return NumberVal(val.AsNumber()), nil
+}
+
+func lenNative(args []Value) (Value, error) {
+	if IsString(args[0]) {
+		return IntVal(utf8.RuneCountInString(AsLiteralString(args[0]))), nil
+	}
+	if IsList(args[0]) {
+		return IntVal(len(AsList(args[0]).items)), nil
+	}
+	if IsMap(args[0]) {
+		return IntVal(len(AsMap(args[0]).keys)), nil
+	}
+	return nil, fmt.Errorf("Can't take the length of %s.", typeName(args[0]))
+}
+
+// argcNative and argvNative expose the arguments passed after the script
+// path on the command line.
+func (vm *VM) argcNative(args []Value) (Value, error) {
+	return IntVal(len(vm.scriptArgs)), nil
+}
+
+func (vm *VM) argvNative(args []Value) (Value, error) {
+	index, err := integerArg(args[0], "Argument index")
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= len(vm.scriptArgs) {
+		return nil, fmt.Errorf("Argument index %d out of range.", index)
+	}
+	return ObjVal{Object: CreateStringObj(vm.scriptArgs[index])}, nil
+}
+
+func typeName(val Value) string {
+	switch val.Type() {
+	case VAL_NIL:
+		return "nil"
+	case VAL_BOOL:
+		return "bool"
+	case VAL_INT:
+		return "int"
+	case VAL_NUMBER:
+		return "float"
+	}
+	switch val.AsObj().Type() {
+	case OBJ_STRING:
+		return "string"
+	case OBJ_CLASS:
+		return "class"
+	case OBJ_INSTANCE:
+		return "instance"
+	case OBJ_LIST:
+		return "list"
+	case OBJ_MAP:
+		return "map"
+	case OBJ_ERROR:
+		return "error"
+	case OBJ_MODULE:
+		return "module"
+	default:
+		return "function"
+	}
+}
+
+// errorNative creates an error object that records where it was created.
+func (vm *VM) errorNative(args []Value) (Value, error) {
+	message := args[0].String()
+	return ObjVal{Object: NewError(message, vm.stackTrace())}, nil
+}
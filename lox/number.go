@@ -0,0 +1,236 @@
+package lox
+
+import (
+	"cmp"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Integers and floats mix by a few fixed rules. Arithmetic on two integers
+// is exact and fails rather than overflow; if either operand is a float the
+// integer is converted and the result is a float. `/` always divides as
+// floats, while `~/` rounds the quotient down and keeps integers exact; `%`
+// is the matching remainder, taking the sign of the divisor. Bitwise
+// operators and shifts only accept integers. Comparisons are exact across
+// the two types.
+
+var errOverflow = errors.New("Integer overflow.")
+
+// parseNumber returns the value of a number literal the scanner accepted: an
+// integer unless it is a decimal with a fraction or an exponent.
+func parseNumber(lexeme string) (Value, error) {
+	text := strings.ReplaceAll(lexeme, "_", "")
+	base := 10
+	if len(text) > 2 && text[0] == '0' {
+		switch text[1] {
+		case 'x', 'X':
+			base = 16
+		case 'b', 'B':
+			base = 2
+		case 'o', 'O':
+			base = 8
+		}
+	}
+	if base != 10 {
+		text = text[2:]
+	} else if strings.ContainsAny(text, ".eE") {
+		val, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, errors.New("Number literal is out of range.")
+		}
+		return NumberVal(val), nil
+	}
+	val, err := strconv.ParseInt(text, base, 64)
+	if err != nil {
+		return nil, errors.New("Integer literal is too large.")
+	}
+	return IntVal(val), nil
+}
+
+// arithmetic applies a binary arithmetic opcode to two numbers.
+func arithmetic(op byte, a, b Value) (Value, error) {
+	if isInt(a) && isInt(b) {
+		return intArithmetic(op, a.AsInt(), b.AsInt())
+	}
+	x, y := a.AsNumber(), b.AsNumber()
+	switch op {
+	case OP_ADD:
+		return NumberVal(x + y), nil
+	case OP_SUBSTRACT:
+		return NumberVal(x - y), nil
+	case OP_MULTIPLY:
+		return NumberVal(x * y), nil
+	case OP_DIVIDE:
+		return NumberVal(x / y), nil
+	case OP_FLOOR_DIVIDE:
+		return NumberVal(math.Floor(x / y)), nil
+	case OP_MODULO:
+		remainder := math.Mod(x, y)
+		if remainder != 0 && (remainder < 0) != (y < 0) {
+			remainder += y
+		}
+		return NumberVal(remainder), nil
+	case OP_POWER:
+		return NumberVal(math.Pow(x, y)), nil
+	}
+	panic("unknown arithmetic operation")
+}
+
+func intArithmetic(op byte, x, y int64) (Value, error) {
+	switch op {
+	case OP_ADD:
+		sum := x + y
+		if (sum > x) != (y > 0) {
+			return nil, errOverflow
+		}
+		return IntVal(sum), nil
+	case OP_SUBSTRACT:
+		diff := x - y
+		if (diff < x) != (y > 0) {
+			return nil, errOverflow
+		}
+		return IntVal(diff), nil
+	case OP_MULTIPLY:
+		if x == 0 || y == 0 {
+			return IntVal(0), nil
+		}
+		product := x * y
+		if product/y != x || (x == math.MinInt64 && y == -1) {
+			return nil, errOverflow
+		}
+		return IntVal(product), nil
+	case OP_DIVIDE:
+		return NumberVal(float64(x) / float64(y)), nil
+	case OP_FLOOR_DIVIDE:
+		if y == 0 {
+			return nil, errors.New("Division by zero.")
+		}
+		if x == math.MinInt64 && y == -1 {
+			return nil, errOverflow
+		}
+		quotient := x / y
+		if x%y != 0 && (x < 0) != (y < 0) {
+			quotient--
+		}
+		return IntVal(quotient), nil
+	case OP_MODULO:
+		if y == 0 {
+			return nil, errors.New("Division by zero.")
+		}
+		remainder := x % y
+		if remainder != 0 && (remainder < 0) != (y < 0) {
+			remainder += y
+		}
+		return IntVal(remainder), nil
+	case OP_POWER:
+		return intPower(x, y)
+	}
+	panic("unknown arithmetic operation")
+}
+
+// intPower raises x to the power y by repeated squaring. A negative
+// exponent gives a float, as the result is usually fractional.
+func intPower(x, y int64) (Value, error) {
+	if y < 0 {
+		return NumberVal(math.Pow(float64(x), float64(y))), nil
+	}
+	result := int64(1)
+	for {
+		if y&1 == 1 {
+			product, err := intArithmetic(OP_MULTIPLY, result, x)
+			if err != nil {
+				return nil, err
+			}
+			result = product.AsInt()
+		}
+		y >>= 1
+		if y == 0 {
+			return IntVal(result), nil
+		}
+		square, err := intArithmetic(OP_MULTIPLY, x, x)
+		if err != nil {
+			return nil, err
+		}
+		x = square.AsInt()
+	}
+}
+
+// bitwise applies a bitwise or shift opcode to two integers. A left shift
+// that loses bits is an overflow, like any other integer operation.
+func bitwise(op byte, x, y int64) (Value, error) {
+	switch op {
+	case OP_BIT_AND:
+		return IntVal(x & y), nil
+	case OP_BIT_OR:
+		return IntVal(x | y), nil
+	case OP_BIT_XOR:
+		return IntVal(x ^ y), nil
+	}
+	if y < 0 {
+		return nil, errors.New("Shift count can't be negative.")
+	}
+	if op == OP_SHIFT_RIGHT {
+		return IntVal(x >> y), nil
+	}
+	shifted := x << y
+	if shifted>>y != x {
+		return nil, errOverflow
+	}
+	return IntVal(shifted), nil
+}
+
+func negate(val Value) (Value, error) {
+	if !isInt(val) {
+		return NumberVal(-val.AsNumber()), nil
+	}
+	if val.AsInt() == math.MinInt64 {
+		return nil, errOverflow
+	}
+	return IntVal(-val.AsInt()), nil
+}
+
+// compareNumbers orders two numbers, returning false if they are unordered
+// because one is NaN. An integer is compared with a float exactly, even when
+// converting it to a float would round.
+func compareNumbers(a, b Value) (int, bool) {
+	switch {
+	case isInt(a) && isInt(b):
+		return cmp.Compare(a.AsInt(), b.AsInt()), true
+	case isInt(a):
+		return compareIntFloat(a.AsInt(), b.AsNumber())
+	case isInt(b):
+		c, ok := compareIntFloat(b.AsInt(), a.AsNumber())
+		return -c, ok
+	}
+	x, y := a.AsNumber(), b.AsNumber()
+	if math.IsNaN(x) || math.IsNaN(y) {
+		return 0, false
+	}
+	return cmp.Compare(x, y), true
+}
+
+func compareIntFloat(i int64, f float64) (int, bool) {
+	switch {
+	case math.IsNaN(f):
+		return 0, false
+	case f >= 1<<63:
+		return -1, true
+	case f < -(1 << 63):
+		return 1, true
+	}
+	whole := int64(f)
+	if i != whole {
+		return cmp.Compare(i, whole), true
+	}
+	return cmp.Compare(0, f-math.Trunc(f)), true
+}
+
+// floatToInt converts f to an integer, dropping any fractional part.
+func floatToInt(f float64) (int64, error) {
+	if math.IsNaN(f) || f >= 1<<63 || f < -(1<<63) {
+		return 0, errors.New("Float is out of integer range.")
+	}
+	return int64(f), nil
+}
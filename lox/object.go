@@ -0,0 +1,357 @@
+package lox
+
+type ObjectType int
+
+const (
+	OBJ_STRING = iota
+	OBJ_FUNCTION
+	OBJ_CLOSURE
+	OBJ_UPVALUE
+	OBJ_CLASS
+	OBJ_INSTANCE
+	OBJ_BOUND_METHOD
+	OBJ_NATIVE
+	OBJ_LIST
+	OBJ_MAP
+	OBJ_ERROR
+	OBJ_MODULE
+	OBJ_THROWN
+)
+
+type Obj interface {
+	Type() ObjectType
+}
+
+type ObjString struct {
+	Length     int
+	Characters string
+}
+
+// ObjFunction describes its parameters with arity, the number of named
+// parameters, and minArity, how many of those have no default. A variadic
+// function collects extra arguments into a list in one more slot. params
+// holds the parameter names so arguments can be passed by name.
+type ObjFunction struct {
+	arity        int
+	minArity     int
+	variadic     bool
+	params       []string
+	upvalueCount int
+	chunk        Chunk
+	name         *ObjString
+	source       *SourceFile
+}
+
+// SourceFile is the code a function was compiled from, kept so that errors
+// can quote the offending line. Text is empty for programs loaded from
+// bytecode.
+type SourceFile struct {
+	Name string
+	Text string
+}
+
+// ObjClosure also remembers the module it was created in, whose globals its
+// code reads and writes.
+type ObjClosure struct {
+	function *ObjFunction
+	upvalues []*ObjUpvalue
+	module   *ObjModule
+}
+
+// ObjUpvalue refers to a variable captured by a closure. While the variable
+// is still alive on the VM stack the upvalue is open and location holds its
+// stack index; once the slot goes away the value moves into closed.
+type ObjUpvalue struct {
+	location int
+	closed   Value
+	isClosed bool
+	next     *ObjUpvalue
+}
+
+type ObjClass struct {
+	name    ObjString
+	methods Table
+}
+
+type ObjInstance struct {
+	klass  *ObjClass
+	fields Table
+}
+
+// ObjBoundMethod pairs a method with the instance it was accessed on, so that
+// calling it later still binds 'this' to that instance.
+type ObjBoundMethod struct {
+	receiver Value
+	method   *ObjClosure
+}
+
+// NativeFn is a Go function exposed to Lox code. It receives the call's
+// arguments and returns the result, or an error that is reported as a
+// runtime error at the call site.
+type NativeFn func(args []Value) (Value, error)
+
+type ObjNative struct {
+	name     string
+	arity    int
+	function NativeFn
+}
+
+type ObjList struct {
+	items []Value
+}
+
+// ObjMap is a hash map that remembers insertion order. Keys are nil,
+// booleans, numbers or strings, all of which compare correctly as Go map
+// keys.
+type ObjMap struct {
+	keys    []Value
+	entries map[Value]Value
+}
+
+// ObjError is the value caught for errors raised by the VM or created with
+// the Error native. trace records the call stack where it was created.
+type ObjError struct {
+	message string
+	trace   []StackFrame
+}
+
+// ObjThrown holds a value thrown out of a try block while its finally
+// clause runs. err describes where it was first thrown, so rethrowing it
+// afterwards reports the original trace.
+type ObjThrown struct {
+	value Value
+	err   *RuntimeError
+}
+
+// ObjModule is a namespace of globals, either the main script's or that of
+// an imported file. function is the module's top-level code and loaded is
+// set once it has finished running.
+type ObjModule struct {
+	name      string
+	globals   map[ObjString]Value
+	constants map[ObjString]constSite
+	exports   map[ObjString]bool
+	function  *ObjFunction
+	loaded    bool
+}
+
+// constSite is the declaration that defined a global constant: the
+// function it is in and the offset just past its OP_DEFINE_CONST. Running
+// the same program again may redefine the constant from the same site.
+type constSite struct {
+	function *ObjFunction
+	ip       int
+}
+
+func (ObjFunction) Type() ObjectType {
+	return OBJ_FUNCTION
+}
+
+func (ObjClosure) Type() ObjectType {
+	return OBJ_CLOSURE
+}
+
+func (ObjUpvalue) Type() ObjectType {
+	return OBJ_UPVALUE
+}
+
+func (ObjClass) Type() ObjectType {
+	return OBJ_CLASS
+}
+
+func (ObjInstance) Type() ObjectType {
+	return OBJ_INSTANCE
+}
+
+func (ObjBoundMethod) Type() ObjectType {
+	return OBJ_BOUND_METHOD
+}
+
+func (ObjNative) Type() ObjectType {
+	return OBJ_NATIVE
+}
+
+func (ObjList) Type() ObjectType {
+	return OBJ_LIST
+}
+
+func (ObjMap) Type() ObjectType {
+	return OBJ_MAP
+}
+
+func (ObjError) Type() ObjectType {
+	return OBJ_ERROR
+}
+
+func (ObjModule) Type() ObjectType {
+	return OBJ_MODULE
+}
+
+func (ObjThrown) Type() ObjectType {
+	return OBJ_THROWN
+}
+
+func (ObjString) Type() ObjectType {
+	return OBJ_STRING
+}
+
+func AsString(val Value) ObjString {
+	if objStr, ok := val.AsObj().(ObjString); ok {
+		return objStr
+	}
+	panic("value is not a string object")
+}
+
+func AsFunc(val Value) *ObjFunction {
+	if objFunc, ok := val.AsObj().(*ObjFunction); ok {
+		return objFunc
+	}
+	panic("value is not a function object")
+}
+
+func AsClosure(val Value) *ObjClosure {
+	if objClosure, ok := val.AsObj().(*ObjClosure); ok {
+		return objClosure
+	}
+	panic("value is not a closure object")
+}
+
+func AsClass(val Value) *ObjClass {
+	if objClass, ok := val.AsObj().(*ObjClass); ok {
+		return objClass
+	}
+	panic("value is not a class object")
+}
+
+func AsInstance(val Value) *ObjInstance {
+	if objInstance, ok := val.AsObj().(*ObjInstance); ok {
+		return objInstance
+	}
+	panic("value is not an instance object")
+}
+
+func AsBoundMethod(val Value) *ObjBoundMethod {
+	if objBound, ok := val.AsObj().(*ObjBoundMethod); ok {
+		return objBound
+	}
+	panic("value is not a bound method object")
+}
+
+func AsNative(val Value) *ObjNative {
+	if objNative, ok := val.AsObj().(*ObjNative); ok {
+		return objNative
+	}
+	panic("value is not a native function object")
+}
+
+func AsList(val Value) *ObjList {
+	if objList, ok := val.AsObj().(*ObjList); ok {
+		return objList
+	}
+	panic("value is not a list object")
+}
+
+func AsMap(val Value) *ObjMap {
+	if objMap, ok := val.AsObj().(*ObjMap); ok {
+		return objMap
+	}
+	panic("value is not a map object")
+}
+
+func AsError(val Value) *ObjError {
+	if objError, ok := val.AsObj().(*ObjError); ok {
+		return objError
+	}
+	panic("value is not an error object")
+}
+
+func AsModule(val Value) *ObjModule {
+	if objModule, ok := val.AsObj().(*ObjModule); ok {
+		return objModule
+	}
+	panic("value is not a module object")
+}
+
+func AsLiteralString(val Value) string {
+	return AsString(val).Characters
+}
+
+func IsObjtype(val Value, t ObjectType) bool {
+	return isObj(val) && val.AsObj().Type() == t
+}
+
+func CreateStringObj(literal string) ObjString {
+	return ObjString{Length: len(literal), Characters: literal}
+}
+
+func NewFunction() *ObjFunction {
+	return &ObjFunction{
+		arity: 0,
+		name:  nil,
+		chunk: Chunk{},
+	}
+}
+
+func NewClosure(function *ObjFunction) *ObjClosure {
+	return &ObjClosure{
+		function: function,
+		upvalues: make([]*ObjUpvalue, function.upvalueCount),
+	}
+}
+
+func NewUpvalue(slot int) *ObjUpvalue {
+	return &ObjUpvalue{
+		location: slot,
+		closed:   NilVal{},
+	}
+}
+
+func NewClass(name ObjString) *ObjClass {
+	return &ObjClass{
+		name:    name,
+		methods: InitTable(),
+	}
+}
+
+func NewInstance(klass *ObjClass) *ObjInstance {
+	return &ObjInstance{
+		klass:  klass,
+		fields: InitTable(),
+	}
+}
+
+func NewBoundMethod(receiver Value, method *ObjClosure) *ObjBoundMethod {
+	return &ObjBoundMethod{
+		receiver: receiver,
+		method:   method,
+	}
+}
+
+func NewNative(name string, arity int, function NativeFn) *ObjNative {
+	return &ObjNative{
+		name:     name,
+		arity:    arity,
+		function: function,
+	}
+}
+
+func NewList(items []Value) *ObjList {
+	return &ObjList{items: items}
+}
+
+func NewMap() *ObjMap {
+	return &ObjMap{entries: make(map[Value]Value)}
+}
+
+func NewError(message string, trace []StackFrame) *ObjError {
+	return &ObjError{message: message, trace: trace}
+}
+
+func NewModule(name string) *ObjModule {
+	return &ObjModule{
+		name:      name,
+		globals:   make(map[ObjString]Value),
+		constants: make(map[ObjString]constSite),
+		exports:   make(map[ObjString]bool),
+	}
+}
@@ -0,0 +1,73 @@
+package lox_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/armadi1809/cloximp/lox"
+)
+
+func TestInputComplete(t *testing.T) {
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{"print 1;", true},
+		{"", true},
+		{"fun f() {", false},
+		{"fun f() {\n  return 1;\n}", true},
+		{"print (1 +", false},
+		{"print (1 +\n 2);", true},
+		{`print "{";`, true},
+		{"var s = \"open", false},
+		{"// {", true},
+		{"}", true},
+	}
+	for _, tt := range tests {
+		if got := lox.InputComplete(tt.source); got != tt.want {
+			t.Errorf("InputComplete(%q) = %v, want %v", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestReplModeKeepsState(t *testing.T) {
+	var out, errOut strings.Builder
+	vm := lox.NewVM(lox.WithReplMode(true), lox.WithOutput(&out), lox.WithErrorOutput(&errOut))
+	lines := []string{
+		"var count = 1;",
+		"fun bump() { count = count + 1; return count; }",
+		"bump();",
+		"class Box { init(v) { this.v = v; } }",
+		"Box(count).v",
+		"count * 10",
+		`"no semicolon"`,
+	}
+	for _, line := range lines {
+		if result := vm.Interpret(line); result != lox.INTERPRET_OK {
+			t.Fatalf("Interpret(%q) = %v: %s", line, result, errOut.String())
+		}
+	}
+	want := "2\n2\n20\nno semicolon\n"
+	if got := out.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestReplModeRecoversFromErrors(t *testing.T) {
+	var out, errOut strings.Builder
+	vm := lox.NewVM(lox.WithReplMode(true), lox.WithOutput(&out), lox.WithErrorOutput(&errOut))
+	vm.Interpret("var x = 3;")
+	if result := vm.Interpret("x +;"); result != lox.INTERPRET_COMPILE_ERROR {
+		t.Errorf("compile error result = %v", result)
+	}
+	if result := vm.Interpret("x();"); result != lox.INTERPRET_RUNTIME_ERROR {
+		t.Errorf("runtime error result = %v", result)
+	}
+	vm.Interpret("x")
+	if got := out.String(); got != "3\n" {
+		t.Errorf("output = %q, want %q", got, "3\n")
+	}
+	if n := strings.Count(errOut.String(), "error: "); n != 2 {
+		t.Errorf("got %d errors, want 2:\n%s", n, errOut.String())
+	}
+}
@@ -0,0 +1,658 @@
+package lox
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	TOKEN_EOF           = "EOF"
+	TOKEN_STRING        = "STRING"
+	TOKEN_INTERPOLATION = "INTERPOLATION"
+	TOKEN_NUMBER        = "NUMBER"
+	TOKEN_IDENTIFIER    = "IDENTIFIER"
+
+	TOKEN_EQUAL = "="
+	TOKEN_ARROW = "=>"
+	TOKEN_PLUS  = "+"
+	TOKEN_MINUS = "-"
+	TOKEN_SLASH = "/"
+	TOKEN_STAR  = "*"
+
+	TOKEN_PERCENT   = "%"
+	TOKEN_STAR_STAR = "**"
+
+	TOKEN_PLUS_EQUAL    = "+="
+	TOKEN_MINUS_EQUAL   = "-="
+	TOKEN_STAR_EQUAL    = "*="
+	TOKEN_SLASH_EQUAL   = "/="
+	TOKEN_PERCENT_EQUAL = "%="
+	TOKEN_PLUS_PLUS     = "++"
+	TOKEN_MINUS_MINUS   = "--"
+
+	TOKEN_AMPERSAND       = "&"
+	TOKEN_PIPE            = "|"
+	TOKEN_CARET           = "^"
+	TOKEN_TILDE           = "~"
+	TOKEN_TILDE_SLASH     = "~/"
+	TOKEN_LESS_LESS       = "<<"
+	TOKEN_GREATER_GREATER = ">>"
+
+	TOKEN_BANG          = "!"
+	TOKEN_BANG_EQUAL    = "!="
+	TOKEN_EQUAL_EQUAL   = "=="
+	TOKEN_GREATER       = ">"
+	TOKEN_GREATER_EQUAL = ">="
+	TOKEN_LESS          = "<"
+	TOKEN_LESS_EQUAL    = "<="
+
+	TOKEN_COLON     = ":"
+	TOKEN_COMMA     = ","
+	TOKEN_SEMICOLON = ";"
+	TOKEN_DOT       = "."
+	TOKEN_ELLIPSIS  = "..."
+
+	TOKEN_LEFT_PAREN    = "("
+	TOKEN_RIGHT_PAREN   = ")"
+	TOKEN_LEFT_BRACE    = "{"
+	TOKEN_RIGHT_BRACE   = "}"
+	TOKEN_LEFT_BRACKET  = "["
+	TOKEN_RIGHT_BRACKET = "]"
+
+	TOKEN_AND      = "AND"
+	TOKEN_BREAK    = "BREAK"
+	TOKEN_CONTINUE = "CONTINUE"
+	TOKEN_CLASS    = "CLASS"
+	TOKEN_ELSE     = "ELSE"
+	TOKEN_FALSE    = "FALSE"
+	TOKEN_FUN      = "FUN"
+	TOKEN_FOR      = "FOR"
+	TOKEN_IF       = "IF"
+	TOKEN_NIL      = "NIL"
+	TOKEN_OR       = "OR"
+	TOKEN_PRINT    = "PRINT"
+	TOKEN_RETURN   = "RETURN"
+	TOKEN_SUPER    = "SUPER"
+	TOKEN_THIS     = "THIS"
+	TOKEN_TRUE     = "TRUE"
+	TOKEN_VAR      = "VAR"
+	TOKEN_WHILE    = "WHILE"
+	TOKEN_FUNCTION = "FUNCTION"
+	TOKEN_LET      = "LET"
+	TOKEN_CONST    = "CONST"
+	TOKEN_TRY      = "TRY"
+	TOKEN_CATCH    = "CATCH"
+	TOKEN_FINALLY  = "FINALLY"
+	TOKEN_THROW    = "THROW"
+	TOKEN_IMPORT   = "IMPORT"
+	TOKEN_EXPORT   = "EXPORT"
+	TOKEN_ERROR    = "ERROR"
+)
+
+type TokenType string
+
+var keywords = map[string]TokenType{
+	"and":      TOKEN_AND,
+	"break":    TOKEN_BREAK,
+	"continue": TOKEN_CONTINUE,
+	"catch":    TOKEN_CATCH,
+	"class":    TOKEN_CLASS,
+	"const":    TOKEN_CONST,
+	"else":     TOKEN_ELSE,
+	"if":       TOKEN_IF,
+	"import":   TOKEN_IMPORT,
+	"let":      TOKEN_LET,
+	"export":   TOKEN_EXPORT,
+	"false":    TOKEN_FALSE,
+	"true":     TOKEN_TRUE,
+	"var":      TOKEN_VAR,
+	"fun":      TOKEN_FUN,
+	"while":    TOKEN_WHILE,
+	"super":    TOKEN_SUPER,
+	"print":    TOKEN_PRINT,
+	"nil":      TOKEN_NIL,
+	"or":       TOKEN_OR,
+	"return":   TOKEN_RETURN,
+	"finally":  TOKEN_FINALLY,
+	"for":      TOKEN_FOR,
+	"this":     TOKEN_THIS,
+	"throw":    TOKEN_THROW,
+	"try":      TOKEN_TRY,
+}
+
+// Start, Current and LineStart are byte offsets into Source. StartLine and
+// StartColumn remember where the token being scanned began, since strings can
+// span several lines.
+type Scanner struct {
+	Source      string
+	Start       int
+	Current     int
+	Line        int
+	LineStart   int
+	StartLine   int
+	StartColumn int
+	// Interpolations holds, for each "${" still open, how many '{' have been
+	// opened inside it, so the matching '}' resumes the enclosing string.
+	Interpolations []int
+}
+
+// Span locates a token in its source. Column is 1-based and counted in
+// characters; Offset and Length are in bytes.
+type Span struct {
+	Line   int
+	Column int
+	Offset int
+	Length int
+}
+
+// Literal holds the decoded text of STRING and INTERPOLATION tokens.
+type Token struct {
+	Type    TokenType
+	Lexeme  string
+	Literal string
+	Line    int
+	Column  int
+	Offset  int
+}
+
+func (tok Token) Span() Span {
+	length := len(tok.Lexeme)
+	if tok.Type == TOKEN_ERROR || tok.Type == TOKEN_EOF {
+		length = 0
+	}
+	return Span{Line: tok.Line, Column: tok.Column, Offset: tok.Offset, Length: length}
+}
+
+func (sc *Scanner) initScanner(source string) {
+	sc.Source = source
+	sc.Start = 0
+	sc.Current = 0
+	sc.Line = 1
+	sc.LineStart = 0
+	sc.Interpolations = nil
+}
+
+func (sc *Scanner) scanToken() Token {
+	sc.skipWhitespaces()
+	sc.Start = sc.Current
+	sc.StartLine = sc.Line
+	sc.StartColumn = utf8.RuneCountInString(sc.Source[sc.LineStart:sc.Start]) + 1
+	if sc.isAtEnd() {
+		return sc.makeToken(TOKEN_EOF)
+	}
+	var tok TokenType
+	c := sc.advance()
+	if isDigit(c) {
+		return sc.scanNumber(c)
+	}
+	switch c {
+	case '(':
+		return sc.makeToken(TOKEN_LEFT_PAREN)
+	case ')':
+		return sc.makeToken(TOKEN_RIGHT_PAREN)
+	case '{':
+		if n := len(sc.Interpolations); n > 0 {
+			sc.Interpolations[n-1]++
+		}
+		return sc.makeToken(TOKEN_LEFT_BRACE)
+	case '}':
+		if n := len(sc.Interpolations); n > 0 {
+			if sc.Interpolations[n-1] == 0 {
+				sc.Interpolations = sc.Interpolations[:n-1]
+				return sc.scanString()
+			}
+			sc.Interpolations[n-1]--
+		}
+		return sc.makeToken(TOKEN_RIGHT_BRACE)
+	case '[':
+		return sc.makeToken(TOKEN_LEFT_BRACKET)
+	case ']':
+		return sc.makeToken(TOKEN_RIGHT_BRACKET)
+	case ';':
+		return sc.makeToken(TOKEN_SEMICOLON)
+	case ',':
+		return sc.makeToken(TOKEN_COMMA)
+	case ':':
+		return sc.makeToken(TOKEN_COLON)
+	case '.':
+		if sc.getCharAtPos(sc.Current) == '.' && sc.getCharAtPos(sc.Current+1) == '.' {
+			sc.advance()
+			sc.advance()
+			return sc.makeToken(TOKEN_ELLIPSIS)
+		}
+		return sc.makeToken(TOKEN_DOT)
+	case '-':
+		tok = TOKEN_MINUS
+		if sc.match('=') {
+			tok = TOKEN_MINUS_EQUAL
+		} else if sc.match('-') {
+			tok = TOKEN_MINUS_MINUS
+		}
+		return sc.makeToken(tok)
+	case '+':
+		tok = TOKEN_PLUS
+		if sc.match('=') {
+			tok = TOKEN_PLUS_EQUAL
+		} else if sc.match('+') {
+			tok = TOKEN_PLUS_PLUS
+		}
+		return sc.makeToken(tok)
+	case '/':
+		tok = TOKEN_SLASH
+		if sc.match('=') {
+			tok = TOKEN_SLASH_EQUAL
+		}
+		return sc.makeToken(tok)
+	case '*':
+		tok = TOKEN_STAR
+		if sc.match('*') {
+			tok = TOKEN_STAR_STAR
+		} else if sc.match('=') {
+			tok = TOKEN_STAR_EQUAL
+		}
+		return sc.makeToken(tok)
+	case '%':
+		tok = TOKEN_PERCENT
+		if sc.match('=') {
+			tok = TOKEN_PERCENT_EQUAL
+		}
+		return sc.makeToken(tok)
+	case '&':
+		return sc.makeToken(TOKEN_AMPERSAND)
+	case '|':
+		return sc.makeToken(TOKEN_PIPE)
+	case '^':
+		return sc.makeToken(TOKEN_CARET)
+	case '~':
+		tok = TOKEN_TILDE
+		if sc.match('/') {
+			tok = TOKEN_TILDE_SLASH
+		}
+		return sc.makeToken(tok)
+	case '!':
+		tok = TOKEN_BANG
+		if sc.match('=') {
+			tok = TOKEN_BANG_EQUAL
+		}
+		return sc.makeToken(tok)
+	case '=':
+		tok = TOKEN_EQUAL
+		if sc.match('=') {
+			tok = TOKEN_EQUAL_EQUAL
+		} else if sc.match('>') {
+			tok = TOKEN_ARROW
+		}
+		return sc.makeToken(tok)
+	case '<':
+		tok = TOKEN_LESS
+		if sc.match('=') {
+			tok = TOKEN_LESS_EQUAL
+		} else if sc.match('<') {
+			tok = TOKEN_LESS_LESS
+		}
+		return sc.makeToken(tok)
+	case '>':
+		tok = TOKEN_GREATER
+		if sc.match('=') {
+			tok = TOKEN_GREATER_EQUAL
+		} else if sc.match('>') {
+			tok = TOKEN_GREATER_GREATER
+		}
+		return sc.makeToken(tok)
+	case '"':
+		return sc.scanString()
+	case '`':
+		return sc.scanRawString()
+	}
+	if isAlpha(c) {
+		return sc.scanIdentifier()
+	}
+	return sc.errorToken("Unexpected character.")
+}
+
+// scanString scans up to the closing quote or up to the next "${", in which
+// case it returns an INTERPOLATION token and the scanner resumes the string
+// when it reaches the matching '}'.
+func (sc *Scanner) scanString() Token {
+	var sb strings.Builder
+	var badEscape *Token
+	for !sc.isAtEnd() && sc.getCharAtPos(sc.Current) != '"' {
+		c := sc.getCharAtPos(sc.Current)
+		switch {
+		case c == '\n':
+			sc.newLine()
+			sb.WriteRune(sc.advance())
+		case c == '$' && sc.getCharAtPos(sc.Current+1) == '{':
+			sc.advance()
+			sc.advance()
+			if badEscape != nil {
+				return *badEscape
+			}
+			sc.Interpolations = append(sc.Interpolations, 0)
+			return sc.makeLiteralToken(TOKEN_INTERPOLATION, sb.String())
+		case c == '\\':
+			backslash := sc.Current
+			sc.advance()
+			if msg := sc.scanEscape(&sb); msg != "" && badEscape == nil {
+				tok := sc.errorTokenAt(msg, backslash)
+				badEscape = &tok
+			}
+		default:
+			sb.WriteRune(sc.advance())
+		}
+	}
+	if sc.isAtEnd() {
+		return sc.errorToken("Unterminated string.")
+	}
+	sc.advance()
+	if badEscape != nil {
+		return *badEscape
+	}
+	return sc.makeLiteralToken(TOKEN_STRING, sb.String())
+}
+
+// scanEscape decodes the escape sequence following a backslash, returning an
+// error message if it is invalid.
+func (sc *Scanner) scanEscape(sb *strings.Builder) string {
+	if sc.isAtEnd() {
+		return "Unterminated string."
+	}
+	c := sc.getCharAtPos(sc.Current)
+	if c == '\n' {
+		sc.newLine()
+	}
+	sc.advance()
+	switch c {
+	case 'n':
+		sb.WriteByte('\n')
+	case 't':
+		sb.WriteByte('\t')
+	case 'r':
+		sb.WriteByte('\r')
+	case '0':
+		sb.WriteByte(0)
+	case '"', '\\', '$':
+		sb.WriteRune(c)
+	case '\n':
+		// A backslash before a newline continues the string on the next line.
+	case 'u':
+		if !sc.match('{') {
+			return "Expect '{' after '\\u'."
+		}
+		start := sc.Current
+		for isHexDigit(sc.getCharAtPos(sc.Current)) {
+			sc.advance()
+		}
+		digits := sc.Source[start:sc.Current]
+		if !sc.match('}') || digits == "" || len(digits) > 6 {
+			return "Invalid unicode escape sequence."
+		}
+		r, _ := strconv.ParseUint(digits, 16, 32)
+		if r > utf8.MaxRune || (r >= 0xD800 && r <= 0xDFFF) {
+			return "Invalid unicode code point."
+		}
+		sb.WriteRune(rune(r))
+	default:
+		return "Invalid escape sequence."
+	}
+	return ""
+}
+
+// scanRawString scans a backquoted string, which may span lines and has no
+// escape sequences or interpolation.
+func (sc *Scanner) scanRawString() Token {
+	for !sc.isAtEnd() && sc.getCharAtPos(sc.Current) != '`' {
+		if sc.getCharAtPos(sc.Current) == '\n' {
+			sc.newLine()
+		}
+		sc.advance()
+	}
+	if sc.isAtEnd() {
+		return sc.errorToken("Unterminated string.")
+	}
+	sc.advance()
+	return sc.makeLiteralToken(TOKEN_STRING, sc.Source[sc.Start+1:sc.Current-1])
+}
+
+// scanNumber scans a number literal whose first digit has been consumed:
+// either a decimal with an optional fraction and exponent, or an integer in
+// hex, binary or octal after a 0x, 0b or 0o prefix. Digits may be grouped
+// with underscores.
+func (sc *Scanner) scanNumber(first int32) Token {
+	if first == '0' {
+		switch sc.getCharAtPos(sc.Current) {
+		case 'x', 'X':
+			return sc.scanRadix("hex", isHexDigit)
+		case 'b', 'B':
+			return sc.scanRadix("binary", isBinaryDigit)
+		case 'o', 'O':
+			return sc.scanRadix("octal", isOctalDigit)
+		}
+	}
+
+	if _, ok := sc.digits(isDigit, 1); !ok {
+		return sc.numberError(SEPARATOR_ERROR)
+	}
+	if sc.getCharAtPos(sc.Current) == '.' && isDigit(sc.getCharAtPos(sc.Current+1)) {
+		sc.advance()
+		if _, ok := sc.digits(isDigit, 0); !ok {
+			return sc.numberError(SEPARATOR_ERROR)
+		}
+	}
+	if c := sc.getCharAtPos(sc.Current); c == 'e' || c == 'E' {
+		sc.advance()
+		if c := sc.getCharAtPos(sc.Current); c == '+' || c == '-' {
+			sc.advance()
+		}
+		count, ok := sc.digits(isDigit, 0)
+		if count == 0 {
+			return sc.numberError("Expect digits in exponent.")
+		}
+		if !ok {
+			return sc.numberError(SEPARATOR_ERROR)
+		}
+	}
+	return sc.endNumber()
+}
+
+const SEPARATOR_ERROR = "Digit separator '_' must be between digits."
+
+func (sc *Scanner) scanRadix(name string, valid func(int32) bool) Token {
+	sc.advance()
+	prefix := sc.Source[sc.Start:sc.Current]
+	count, ok := sc.digits(valid, 0)
+	if count == 0 && sc.getCharAtPos(sc.Current) != '_' {
+		return sc.numberError(fmt.Sprintf("Expect %s digits after '%s'.", name, prefix))
+	}
+	if !ok {
+		return sc.numberError(SEPARATOR_ERROR)
+	}
+	return sc.endNumber()
+}
+
+// digits consumes a run of digits accepted by valid, separated by single
+// underscores, and returns how many digits it read. count is the number of
+// digits already consumed. ok is false if an underscore isn't between two
+// digits.
+func (sc *Scanner) digits(valid func(int32) bool, count int) (int, bool) {
+	for {
+		c := sc.getCharAtPos(sc.Current)
+		switch {
+		case valid(c):
+			count++
+		case c == '_':
+			if count == 0 || !valid(sc.getCharAtPos(sc.Current+1)) {
+				return count, false
+			}
+		default:
+			return count, true
+		}
+		sc.advance()
+	}
+}
+
+// endNumber rejects a literal that runs straight into letters or digits it
+// can't contain, such as 0b12 or 3px.
+func (sc *Scanner) endNumber() Token {
+	if c := sc.getCharAtPos(sc.Current); isAlpha(c) || isDigit(c) {
+		return sc.numberError("Invalid character in number literal.")
+	}
+	return sc.makeToken(TOKEN_NUMBER)
+}
+
+// numberError skips the rest of a malformed literal so scanning resumes
+// after it.
+func (sc *Scanner) numberError(message string) Token {
+	for c := sc.getCharAtPos(sc.Current); isAlpha(c) || isDigit(c); c = sc.getCharAtPos(sc.Current) {
+		sc.advance()
+	}
+	return sc.errorToken(message)
+}
+
+func (sc *Scanner) scanIdentifier() Token {
+
+	for isAlpha(sc.getCharAtPos(sc.Current)) || isDigit(sc.getCharAtPos(sc.Current)) {
+		sc.advance()
+	}
+	iden := sc.Source[sc.Start:sc.Current]
+	var tok TokenType = TOKEN_IDENTIFIER
+	if keyword, ok := keywords[iden]; ok {
+		tok = keyword
+	}
+	return sc.makeToken(tok)
+}
+
+// peekToken scans the token after the current one without consuming it.
+func (sc *Scanner) peekToken() Token {
+	saved := sc.snapshot()
+	tok := sc.scanToken()
+	*sc = saved
+	return tok
+}
+
+// snapshot copies the scanner state so that scanning can be undone by
+// assigning the copy back.
+func (sc *Scanner) snapshot() Scanner {
+	saved := *sc
+	saved.Interpolations = append([]int(nil), sc.Interpolations...)
+	return saved
+}
+
+func (sc *Scanner) isAtEnd() bool {
+	return sc.Current >= len(sc.Source)
+}
+
+func (sc *Scanner) makeToken(tokenType TokenType) Token {
+	return Token{
+		Type:   tokenType,
+		Lexeme: sc.Source[sc.Start:sc.Current],
+		Line:   sc.StartLine,
+		Column: sc.StartColumn,
+		Offset: sc.Start,
+	}
+}
+
+func (sc *Scanner) makeLiteralToken(tokenType TokenType, literal string) Token {
+	tok := sc.makeToken(tokenType)
+	tok.Literal = literal
+	return tok
+}
+
+func (sc *Scanner) errorToken(message string) Token {
+	return Token{
+		Type:   TOKEN_ERROR,
+		Lexeme: message,
+		Line:   sc.StartLine,
+		Column: sc.StartColumn,
+		Offset: sc.Start,
+	}
+}
+
+// errorTokenAt reports message at offset, which must be on the current line.
+func (sc *Scanner) errorTokenAt(message string, offset int) Token {
+	return Token{
+		Type:   TOKEN_ERROR,
+		Lexeme: message,
+		Line:   sc.Line,
+		Column: utf8.RuneCountInString(sc.Source[sc.LineStart:offset]) + 1,
+		Offset: offset,
+	}
+}
+
+// newLine must be called while Current is on a '\n' that is about to be
+// consumed.
+func (sc *Scanner) newLine() {
+	sc.Line++
+	sc.LineStart = sc.Current + 1
+}
+
+func (sc *Scanner) advance() int32 {
+	c, size := utf8.DecodeRuneInString(sc.Source[sc.Current:])
+	sc.Current += size
+	return c
+}
+
+func (sc *Scanner) match(expected int32) bool {
+	if sc.isAtEnd() {
+		return false
+	}
+	if sc.getCharAtPos(sc.Current) != expected {
+		return false
+	}
+	sc.Current++
+	return true
+
+}
+
+func (sc *Scanner) skipWhitespaces() {
+	for {
+		c := sc.getCharAtPos(sc.Current)
+		switch c {
+		case ' ', '\t', '\r':
+			sc.advance()
+		case '\n':
+			sc.newLine()
+			sc.advance()
+		case '/':
+			if sc.getCharAtPos(sc.Current+1) == '/' {
+				for !sc.isAtEnd() && sc.getCharAtPos(sc.Current) != '\n' {
+					sc.advance()
+				}
+			} else {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (sc *Scanner) getCharAtPos(pos int) int32 {
+	if pos > len(sc.Source)-1 {
+		return '\x00'
+	}
+	c, _ := utf8.DecodeRuneInString(sc.Source[pos:])
+	return c
+}
+
+func isDigit(c int32) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c int32) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isBinaryDigit(c int32) bool {
+	return c == '0' || c == '1'
+}
+
+func isOctalDigit(c int32) bool {
+	return c >= '0' && c <= '7'
+}
+
+func isAlpha(c int32) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+}
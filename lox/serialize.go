@@ -0,0 +1,258 @@
+package lox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Compiled programs are stored as a magic header followed by the source name
+// and the top-level function. A function is written as its name, parameters,
+// upvalue count, code, span table and constants; nested functions appear
+// inline as constants. The source text itself is not stored.
+const (
+	BYTECODE_MAGIC   = "LOXC"
+	BYTECODE_VERSION = 5
+)
+
+const (
+	CONST_NIL byte = iota
+	CONST_FALSE
+	CONST_TRUE
+	CONST_NUMBER
+	CONST_STRING
+	CONST_FUNCTION
+	CONST_INT
+)
+
+func IsBytecode(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(BYTECODE_MAGIC))
+}
+
+func WriteBytecode(w io.Writer, program *Program) error {
+	bw := &bytecodeWriter{}
+	bw.buf = append(bw.buf, BYTECODE_MAGIC...)
+	bw.buf = append(bw.buf, BYTECODE_VERSION)
+	bw.writeString(program.function.source.Name)
+	if err := bw.writeFunction(program.function); err != nil {
+		return err
+	}
+	_, err := w.Write(bw.buf)
+	return err
+}
+
+func ReadBytecode(data []byte) (*Program, error) {
+	if !IsBytecode(data) {
+		return nil, errors.New("not a compiled Lox program")
+	}
+	br := &bytecodeReader{data: data, pos: len(BYTECODE_MAGIC)}
+	if version := br.readByte(); version != BYTECODE_VERSION {
+		return nil, fmt.Errorf("unsupported bytecode version %d", version)
+	}
+	br.source = &SourceFile{Name: br.readString()}
+	function := br.readFunction()
+	if br.err != nil {
+		return nil, br.err
+	}
+	return &Program{function: function}, nil
+}
+
+type bytecodeWriter struct {
+	buf []byte
+}
+
+func (bw *bytecodeWriter) writeUint(n int) {
+	bw.buf = binary.AppendUvarint(bw.buf, uint64(n))
+}
+
+func (bw *bytecodeWriter) writeString(s string) {
+	bw.writeUint(len(s))
+	bw.buf = append(bw.buf, s...)
+}
+
+func (bw *bytecodeWriter) writeFunction(function *ObjFunction) error {
+	if function.name == nil {
+		bw.buf = append(bw.buf, 0)
+	} else {
+		bw.buf = append(bw.buf, 1)
+		bw.writeString(function.name.Characters)
+	}
+	bw.writeUint(function.arity)
+	bw.writeUint(function.minArity)
+	if function.variadic {
+		bw.buf = append(bw.buf, 1)
+	} else {
+		bw.buf = append(bw.buf, 0)
+	}
+	for _, param := range function.params {
+		bw.writeString(param)
+	}
+	bw.writeUint(function.upvalueCount)
+
+	chunk := &function.chunk
+	bw.writeUint(len(chunk.Code))
+	bw.buf = append(bw.buf, chunk.Code...)
+	for _, span := range chunk.spans {
+		bw.writeUint(span.Line)
+		bw.writeUint(span.Column)
+		bw.writeUint(span.Offset)
+		bw.writeUint(span.Length)
+	}
+
+	bw.writeUint(len(chunk.Constants.values))
+	for _, constant := range chunk.Constants.values {
+		if err := bw.writeConstant(constant); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (bw *bytecodeWriter) writeConstant(val Value) error {
+	switch {
+	case isNil(val):
+		bw.buf = append(bw.buf, CONST_NIL)
+	case isBool(val):
+		if val.AsBoolean() {
+			bw.buf = append(bw.buf, CONST_TRUE)
+		} else {
+			bw.buf = append(bw.buf, CONST_FALSE)
+		}
+	case isInt(val):
+		bw.buf = append(bw.buf, CONST_INT)
+		bw.buf = binary.AppendVarint(bw.buf, val.AsInt())
+	case isFloat(val):
+		bw.buf = append(bw.buf, CONST_NUMBER)
+		bw.buf = binary.LittleEndian.AppendUint64(bw.buf, math.Float64bits(val.AsNumber()))
+	case IsString(val):
+		bw.buf = append(bw.buf, CONST_STRING)
+		bw.writeString(AsLiteralString(val))
+	case IsFunction(val):
+		bw.buf = append(bw.buf, CONST_FUNCTION)
+		return bw.writeFunction(AsFunc(val))
+	default:
+		return fmt.Errorf("can't serialize constant %s", val.String())
+	}
+	return nil
+}
+
+type bytecodeReader struct {
+	data   []byte
+	pos    int
+	err    error
+	source *SourceFile
+}
+
+func (br *bytecodeReader) fail() {
+	if br.err == nil {
+		br.err = errors.New("truncated or corrupt bytecode")
+	}
+	br.pos = len(br.data)
+}
+
+func (br *bytecodeReader) readByte() byte {
+	if br.pos >= len(br.data) {
+		br.fail()
+		return 0
+	}
+	b := br.data[br.pos]
+	br.pos++
+	return b
+}
+
+func (br *bytecodeReader) readBytes(n int) []byte {
+	if n < 0 || br.pos+n > len(br.data) {
+		br.fail()
+		return nil
+	}
+	b := br.data[br.pos : br.pos+n]
+	br.pos += n
+	return b
+}
+
+func (br *bytecodeReader) readUint() int {
+	n, size := binary.Uvarint(br.data[br.pos:])
+	if size <= 0 || n > math.MaxInt32 {
+		br.fail()
+		return 0
+	}
+	br.pos += size
+	return int(n)
+}
+
+func (br *bytecodeReader) readInt() int64 {
+	n, size := binary.Varint(br.data[br.pos:])
+	if size <= 0 {
+		br.fail()
+		return 0
+	}
+	br.pos += size
+	return n
+}
+
+func (br *bytecodeReader) readString() string {
+	return string(br.readBytes(br.readUint()))
+}
+
+func (br *bytecodeReader) readFunction() *ObjFunction {
+	function := NewFunction()
+	function.source = br.source
+	if br.readByte() == 1 {
+		name := CreateStringObj(br.readString())
+		function.name = &name
+	}
+	function.arity = br.readUint()
+	function.minArity = br.readUint()
+	function.variadic = br.readByte() == 1
+	for i := 0; i < function.arity && br.err == nil; i++ {
+		function.params = append(function.params, br.readString())
+	}
+	function.upvalueCount = br.readUint()
+
+	codeLen := br.readUint()
+	function.chunk.Code = append([]byte(nil), br.readBytes(codeLen)...)
+	function.chunk.spans = make([]Span, 0, codeLen)
+	for i := 0; i < codeLen && br.err == nil; i++ {
+		span := Span{
+			Line:   br.readUint(),
+			Column: br.readUint(),
+			Offset: br.readUint(),
+			Length: br.readUint(),
+		}
+		function.chunk.spans = append(function.chunk.spans, span)
+	}
+
+	constantCount := br.readUint()
+	for i := 0; i < constantCount && br.err == nil; i++ {
+		function.chunk.AddConstant(br.readConstant())
+	}
+	return function
+}
+
+func (br *bytecodeReader) readConstant() Value {
+	switch br.readByte() {
+	case CONST_NIL:
+		return NilVal{}
+	case CONST_FALSE:
+		return BoolVal(false)
+	case CONST_TRUE:
+		return BoolVal(true)
+	case CONST_NUMBER:
+		bits := br.readBytes(8)
+		if bits == nil {
+			return NilVal{}
+		}
+		return NumberVal(math.Float64frombits(binary.LittleEndian.Uint64(bits)))
+	case CONST_INT:
+		return IntVal(br.readInt())
+	case CONST_STRING:
+		return ObjVal{Object: CreateStringObj(br.readString())}
+	case CONST_FUNCTION:
+		return ObjVal{Object: br.readFunction()}
+	}
+	br.fail()
+	return NilVal{}
+}
@@ -0,0 +1,360 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// type Value float64
+
+type ValueType int
+
+const (
+	VAL_BOOL = iota
+	VAL_NIL
+	VAL_NUMBER
+	VAL_OBJ
+	VAL_INT
+)
+
+type Value interface {
+	Type() ValueType
+	AsBoolean() bool
+	AsNumber() float64
+	AsInt() int64
+	AsObj() Obj
+	Print()
+	String() string
+}
+
+type NilVal struct{}
+
+func (nv NilVal) Type() ValueType {
+	return VAL_NIL
+}
+
+func (nv NilVal) AsBoolean() bool {
+	panic("nil value is not a boolean!")
+}
+
+func (nv NilVal) AsNumber() float64 {
+	panic("nil value is not a number!")
+}
+
+func (nv NilVal) AsInt() int64 {
+	panic("nil value is not an integer!")
+}
+
+func (nv NilVal) AsObj() Obj {
+	panic("nil value is not an object")
+}
+
+func (nv NilVal) Print() {
+	fmt.Print(nv.String())
+}
+
+func (nv NilVal) String() string {
+	return "nil"
+}
+
+type BoolVal bool
+
+func (bv BoolVal) Type() ValueType {
+	return VAL_BOOL
+}
+
+func (bv BoolVal) AsBoolean() bool {
+	return bool(bv)
+}
+
+func (bv BoolVal) AsNumber() float64 {
+	panic("bool value is not a number!")
+}
+
+func (bv BoolVal) AsInt() int64 {
+	panic("bool value is not an integer!")
+}
+
+func (nv BoolVal) AsObj() Obj {
+	panic("bool value is not an object")
+}
+
+func (bv BoolVal) Print() {
+	fmt.Print(bv.String())
+}
+
+func (bv BoolVal) String() string {
+	return fmt.Sprintf("%t", bool(bv))
+}
+
+type NumberVal float64
+
+func (nv NumberVal) Type() ValueType {
+	return VAL_NUMBER
+}
+
+func (nv NumberVal) AsBoolean() bool {
+	panic("number value is not a boolean!")
+}
+
+func (nv NumberVal) AsNumber() float64 {
+	return float64(nv)
+}
+
+func (nv NumberVal) AsInt() int64 {
+	panic("float value is not an integer!")
+}
+
+func (nv NumberVal) AsObj() Obj {
+	panic("number is not an object")
+}
+
+func (nv NumberVal) Print() {
+	fmt.Print(nv.String())
+}
+
+// String writes a float in plain decimal unless it is very large or very
+// small, and always with a fractional part, so 4.0 can't be mistaken for
+// the integer 4.
+func (nv NumberVal) String() string {
+	f := float64(nv)
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	text := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.Contains(text, ".") {
+		text += ".0"
+	}
+	return text
+}
+
+// IntVal is an exact 64-bit integer. Arithmetic between two integers stays
+// an integer and fails on overflow; mixing in a float gives a float.
+type IntVal int64
+
+func (iv IntVal) Type() ValueType {
+	return VAL_INT
+}
+
+func (iv IntVal) AsBoolean() bool {
+	panic("integer value is not a boolean!")
+}
+
+func (iv IntVal) AsNumber() float64 {
+	return float64(iv)
+}
+
+func (iv IntVal) AsInt() int64 {
+	return int64(iv)
+}
+
+func (iv IntVal) AsObj() Obj {
+	panic("integer is not an object")
+}
+
+func (iv IntVal) Print() {
+	fmt.Print(iv.String())
+}
+
+func (iv IntVal) String() string {
+	return strconv.FormatInt(int64(iv), 10)
+}
+
+type ObjVal struct {
+	Object Obj
+}
+
+func (ob ObjVal) Type() ValueType {
+	return VAL_OBJ
+}
+
+func (ob ObjVal) AsBoolean() bool {
+	panic("object value is not a boolean!")
+}
+
+func (ob ObjVal) AsNumber() float64 {
+	panic("object value is not a number")
+}
+
+func (ob ObjVal) AsInt() int64 {
+	panic("object value is not an integer")
+}
+
+func (ob ObjVal) AsObj() Obj {
+	return ob.Object
+}
+
+func (ob ObjVal) Print() {
+	fmt.Print(ob.String())
+}
+
+func (ob ObjVal) String() string {
+	switch ob.Object.Type() {
+	case OBJ_STRING:
+		return AsLiteralString(ob)
+	case OBJ_FUNCTION:
+		return functionString(AsFunc(ob))
+	case OBJ_CLOSURE:
+		return functionString(AsClosure(ob).function)
+	case OBJ_UPVALUE:
+		return "upvalue"
+	case OBJ_CLASS:
+		return AsClass(ob).name.Characters
+	case OBJ_INSTANCE:
+		return fmt.Sprintf("%s instance", AsInstance(ob).klass.name.Characters)
+	case OBJ_BOUND_METHOD:
+		return functionString(AsBoundMethod(ob).method.function)
+	case OBJ_LIST:
+		list := AsList(ob)
+		parts := make([]string, len(list.items))
+		for i, item := range list.items {
+			parts[i] = reprString(item)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case OBJ_MAP:
+		m := AsMap(ob)
+		parts := make([]string, len(m.keys))
+		for i, key := range m.keys {
+			parts[i] = reprString(key) + ": " + reprString(m.entries[key])
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	case OBJ_NATIVE:
+		return fmt.Sprintf("<native fn %s>", AsNative(ob).name)
+	case OBJ_ERROR:
+		return "Error: " + AsError(ob).message
+	case OBJ_MODULE:
+		return fmt.Sprintf("<module %s>", AsModule(ob).name)
+	case OBJ_THROWN:
+		return "thrown " + reprString(ob.Object.(*ObjThrown).value)
+	}
+	return ""
+}
+
+// reprString is like String, but quotes strings so they stand out when
+// printed inside a collection.
+func reprString(val Value) string {
+	if IsString(val) {
+		return strconv.Quote(AsLiteralString(val))
+	}
+	return val.String()
+}
+
+// missingArg fills the slot of an optional parameter the caller left out
+// until the function's prologue evaluates its default. It behaves as nil if
+// it is ever observed.
+type missingArg struct{ NilVal }
+
+func functionName(funcObj *ObjFunction) string {
+	if funcObj.name == nil {
+		return "script"
+	}
+	return funcObj.name.Characters
+}
+
+func functionString(funcObj *ObjFunction) string {
+	if funcObj.name == nil {
+		return "<script>"
+	}
+	return fmt.Sprintf("<fn %s>", funcObj.name.Characters)
+}
+
+func isBool(v Value) bool {
+	return v.Type() == VAL_BOOL
+}
+
+// isNumber reports whether v is an integer or a float.
+func isNumber(v Value) bool {
+	return v.Type() == VAL_NUMBER || v.Type() == VAL_INT
+}
+
+func isInt(v Value) bool {
+	return v.Type() == VAL_INT
+}
+
+func isFloat(v Value) bool {
+	return v.Type() == VAL_NUMBER
+}
+
+func isNil(v Value) bool {
+	return v.Type() == VAL_NIL
+}
+
+func isObj(v Value) bool {
+	return v.Type() == VAL_OBJ
+}
+
+func IsString(val Value) bool {
+	return IsObjtype(val, OBJ_STRING)
+}
+
+func IsFunction(val Value) bool {
+	return IsObjtype(val, OBJ_FUNCTION)
+}
+
+func IsClosure(val Value) bool {
+	return IsObjtype(val, OBJ_CLOSURE)
+}
+
+func IsBoundMethod(val Value) bool {
+	return IsObjtype(val, OBJ_BOUND_METHOD)
+}
+
+func IsNative(val Value) bool {
+	return IsObjtype(val, OBJ_NATIVE)
+}
+
+func IsList(val Value) bool {
+	return IsObjtype(val, OBJ_LIST)
+}
+
+func IsMap(val Value) bool {
+	return IsObjtype(val, OBJ_MAP)
+}
+
+func IsError(val Value) bool {
+	return IsObjtype(val, OBJ_ERROR)
+}
+
+func IsModule(val Value) bool {
+	return IsObjtype(val, OBJ_MODULE)
+}
+
+func IsClass(val Value) bool {
+	return IsObjtype(val, OBJ_CLASS)
+}
+
+func IsInstance(val Value) bool {
+	return IsObjtype(val, OBJ_INSTANCE)
+}
+
+func valuesEqual(a, b Value) bool {
+	if isNumber(a) && isNumber(b) {
+		order, ok := compareNumbers(a, b)
+		return ok && order == 0
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+	switch a.Type() {
+	case VAL_BOOL:
+		return a.AsBoolean() == b.AsBoolean()
+	case VAL_NIL:
+		return true
+	case VAL_OBJ:
+		if IsString(a) && IsString(b) {
+			return AsString(a).Characters == AsString(b).Characters
+		}
+		return a.AsObj() == b.AsObj()
+	}
+
+	return false
+}
+
+type ValueArray struct {
+	values []Value
+}
@@ -0,0 +1,995 @@
+package lox
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+)
+
+type InterpretResult byte
+
+const FRAME_MAX = 64
+const DEFAULT_MAX_ERRORS = 50
+
+const (
+	INTERPRET_OK = iota
+	INTERPRET_COMPILE_ERROR
+	INTERPRET_RUNTIME_ERROR
+)
+
+type CallFrame struct {
+	closure *ObjClosure
+	ip      int
+	slots   int
+}
+
+// ExceptionHandler is pushed by OP_TRY. When a value is thrown the VM drops
+// the frames and stack values above the try statement and resumes at ip in
+// the frame that ran it, with the thrown value on top of the stack.
+type ExceptionHandler struct {
+	frameCount int
+	stackLen   int
+	ip         int
+}
+
+type VM struct {
+	frames       []CallFrame
+	frameCount   int
+	stack        []Value
+	compiler     *Compiler
+	globals      map[ObjString]Value
+	builtins     map[ObjString]Value
+	main         *ObjModule
+	modules      map[string]*ObjModule
+	openUpvalues *ObjUpvalue
+	initString   ObjString
+	replMode     bool
+	debug        DebugOptions
+	scriptArgs   []string
+	stdout       io.Writer
+	stderr       io.Writer
+	result       Value
+	lastError    *RuntimeError
+	handlers     []ExceptionHandler
+	// thrown is the value being thrown while the VM unwinds to a handler.
+	thrown    Value
+	maxErrors int
+}
+
+// NewVM creates a VM with the standard natives defined. By default print
+// statements write to stdout and diagnostics to stderr.
+func NewVM(opts ...Option) *VM {
+	vm := &VM{
+		stdout:    os.Stdout,
+		stderr:    os.Stderr,
+		maxErrors: DEFAULT_MAX_ERRORS,
+	}
+	vm.initVM()
+	for _, opt := range opts {
+		opt(vm)
+	}
+	return vm
+}
+
+func (vm *VM) initVM() {
+	vm.resetStack()
+	vm.main = NewModule("<script>")
+	vm.main.loaded = true
+	vm.globals = vm.main.globals
+	vm.builtins = make(map[ObjString]Value)
+	vm.modules = make(map[string]*ObjModule)
+	vm.frameCount = 0
+	vm.initString = CreateStringObj("init")
+	vm.defineNatives()
+}
+
+// Interpret compiles and runs source on this VM, printing any errors to the
+// VM's error output. Globals defined by earlier calls stay visible, which is
+// what lets the REPL build up state line by line.
+func (vm *VM) Interpret(source string) InterpretResult {
+	function, err := vm.compile(&SourceFile{Name: "<script>", Text: source})
+	if err != nil {
+		fmt.Fprintln(vm.stderr, err)
+		return INTERPRET_COMPILE_ERROR
+	}
+	result := vm.runFunction(function)
+	if result == INTERPRET_RUNTIME_ERROR {
+		fmt.Fprintln(vm.stderr, vm.lastError)
+	}
+	return result
+}
+
+// compile turns source into the top-level script function.
+func (vm *VM) compile(source *SourceFile) (*ObjFunction, error) {
+	vm.compiler = vm.newCompiler()
+	vm.compiler.ReplMode = vm.replMode
+	return vm.compiler.compile(source)
+}
+
+func (vm *VM) newCompiler() *Compiler {
+	compiler := &Compiler{}
+	compiler.initCompiler(TYPE_SCRIPT)
+	compiler.Debug = vm.debug
+	compiler.Ps.maxErrors = vm.maxErrors
+	return compiler
+}
+
+func (vm *VM) runFunction(function *ObjFunction) InterpretResult {
+	vm.resetStack()
+	vm.result = NilVal{}
+	vm.pushStack(ObjVal{Object: function})
+	closure := NewClosure(function)
+	closure.module = vm.main
+	vm.popStack()
+	vm.pushStack(ObjVal{Object: closure})
+	vm.call(closure, 0)
+
+	return vm.run()
+}
+
+// run executes until the script returns or a thrown value escapes every
+// handler.
+func (vm *VM) run() InterpretResult {
+	for {
+		result := vm.execute()
+		if result != INTERPRET_RUNTIME_ERROR || !vm.catchThrown() {
+			return result
+		}
+	}
+}
+
+// catchThrown unwinds to the innermost exception handler and pushes the
+// thrown value. It reports false, resetting the VM, if there is no handler.
+func (vm *VM) catchThrown() bool {
+	if len(vm.handlers) == 0 {
+		vm.resetStack()
+		return false
+	}
+	handler := vm.handlers[len(vm.handlers)-1]
+	vm.handlers = vm.handlers[:len(vm.handlers)-1]
+	vm.closeUpvalues(handler.stackLen)
+	vm.frameCount = handler.frameCount
+	vm.frames = vm.frames[:vm.frameCount]
+	vm.stack = vm.stack[:handler.stackLen]
+	vm.pushStack(vm.thrown)
+	vm.getCurrentFrame().ip = handler.ip
+	return true
+}
+
+func (vm *VM) execute() InterpretResult {
+	frame := vm.getCurrentFrame()
+	for {
+		if vm.debug.TraceExecution {
+			vm.traceInstruction(frame)
+		}
+		inst := vm.readByte()
+		switch inst {
+		case OP_RETURN:
+			{
+				result := vm.popStack()
+				vm.closeUpvalues(frame.slots)
+				if module := frame.closure.module; frame.closure.function == module.function {
+					module.loaded = true
+				}
+				vm.frameCount--
+				vm.frames = vm.frames[:vm.frameCount]
+				if vm.frameCount == 0 {
+					vm.popStack()
+					vm.result = result
+					return INTERPRET_OK
+				}
+
+				vm.stack = vm.stack[:frame.slots]
+
+				vm.pushStack(result)
+
+				frame = &vm.frames[vm.frameCount-1]
+				break
+			}
+		case OP_CONSTANT:
+			constant := vm.readConstant()
+			vm.pushStack(constant)
+		case OP_NEGATE:
+			if !isNumber(vm.peek(0)) {
+				vm.runtimeError("Operand must be a number")
+				return INTERPRET_RUNTIME_ERROR
+			}
+			result, err := negate(vm.popStack())
+			if err != nil {
+				vm.runtimeError("%s", err.Error())
+				return INTERPRET_RUNTIME_ERROR
+			}
+			vm.pushStack(result)
+		case OP_NOT:
+			vm.pushStack(BoolVal(isFalsey(vm.popStack())))
+		case OP_ADD:
+			if !vm.performBinaryOp(inst) {
+				return INTERPRET_RUNTIME_ERROR
+			}
+
+		case OP_DIVIDE:
+			if !vm.performBinaryOp(inst) {
+				return INTERPRET_RUNTIME_ERROR
+			}
+		case OP_MULTIPLY:
+			if !vm.performBinaryOp(inst) {
+				return INTERPRET_RUNTIME_ERROR
+			}
+		case OP_SUBSTRACT:
+			if !vm.performBinaryOp(inst) {
+				return INTERPRET_RUNTIME_ERROR
+			}
+		case OP_FLOOR_DIVIDE, OP_MODULO, OP_POWER,
+			OP_BIT_AND, OP_BIT_OR, OP_BIT_XOR, OP_SHIFT_LEFT, OP_SHIFT_RIGHT:
+			if !vm.performBinaryOp(inst) {
+				return INTERPRET_RUNTIME_ERROR
+			}
+		case OP_BIT_NOT:
+			if !isInt(vm.peek(0)) {
+				vm.runtimeError("Operand must be an integer.")
+				return INTERPRET_RUNTIME_ERROR
+			}
+			vm.pushStack(IntVal(^vm.popStack().AsInt()))
+		case OP_NIL:
+			vm.pushStack(NilVal{})
+		case OP_TRUE:
+			vm.pushStack(BoolVal(true))
+		case OP_FALSE:
+			vm.pushStack(BoolVal(false))
+		case OP_POP:
+			vm.popStack()
+		case OP_DUP:
+			vm.pushStack(vm.peek(0))
+		case OP_DEFINE_GLOBAL, OP_DEFINE_CONST:
+			name := vm.readString()
+			module := frame.closure.module
+			site := constSite{frame.closure.function, frame.ip}
+			if defined, ok := module.constants[name]; ok && defined != site {
+				vm.runtimeError("Can't redefine constant '%s'.", name.Characters)
+				return INTERPRET_RUNTIME_ERROR
+			}
+			module.globals[name] = vm.peek(0)
+			if inst == OP_DEFINE_CONST {
+				module.constants[name] = site
+			} else {
+				delete(module.constants, name)
+			}
+			vm.popStack()
+		case OP_EQUAL:
+			vm.pushStack(BoolVal(valuesEqual(vm.popStack(), vm.popStack())))
+		case OP_LESS:
+			if !vm.performBinaryOp(inst) {
+				return INTERPRET_RUNTIME_ERROR
+			}
+		case OP_GREATER:
+			if !vm.performBinaryOp(inst) {
+				return INTERPRET_RUNTIME_ERROR
+			}
+		case OP_PRINT:
+			fmt.Fprintln(vm.stdout, vm.popStack().String())
+		case OP_GET_GLOBAL:
+			name := vm.readString()
+			val, ok := frame.closure.module.globals[name]
+			if !ok {
+				val, ok = vm.builtins[name]
+			}
+			if !ok {
+				vm.runtimeError("Undefined variable '%s'.", name.Characters)
+				return INTERPRET_RUNTIME_ERROR
+			}
+			vm.pushStack(val)
+		case OP_GET_LOCAL:
+			slot := vm.readByte()
+			vm.pushStack(vm.stack[frame.slots+int(slot)])
+		case OP_SET_LOCAL:
+			slot := vm.readByte()
+			vm.stack[frame.slots+int(slot)] = vm.peek(0)
+		case OP_SET_GLOBAL:
+			name := vm.readString()
+			module := frame.closure.module
+			if _, ok := module.globals[name]; !ok {
+				vm.runtimeError("Undefined variable '%s'.", name.Characters)
+				return INTERPRET_RUNTIME_ERROR
+			}
+			if _, ok := module.constants[name]; ok {
+				vm.runtimeError("Can't assign to constant '%s'.", name.Characters)
+				return INTERPRET_RUNTIME_ERROR
+			}
+			module.globals[name] = vm.peek(0)
+		case OP_JUMP_IF_FALSE:
+			offset := vm.readShort()
+			if isFalsey(vm.peek(0)) {
+				frame.ip += offset
+			}
+		case OP_JUMP:
+			offset := vm.readShort()
+			frame.ip += offset
+		case OP_LOOP:
+			offset := vm.readShort()
+			frame.ip -= offset
+		case OP_CALL:
+			argCount := int(vm.readByte())
+			if !vm.callValue(vm.peek(argCount), argCount) {
+				return INTERPRET_RUNTIME_ERROR
+			}
+			frame = &vm.frames[vm.frameCount-1]
+		case OP_CALL_NAMED:
+			argCount := int(vm.readByte())
+			names := make([]ObjString, vm.readByte())
+			for i := range names {
+				names[i] = vm.readString()
+			}
+			if !vm.callNamed(vm.peek(argCount), argCount, names) {
+				return INTERPRET_RUNTIME_ERROR
+			}
+			frame = &vm.frames[vm.frameCount-1]
+		case OP_SKIP_DEFAULT:
+			slot := int(vm.readByte())
+			offset := vm.readShort()
+			if _, missing := vm.stack[frame.slots+slot].(missingArg); !missing {
+				frame.ip += offset
+			}
+		case OP_CLOSURE:
+			function := AsFunc(vm.readConstant())
+			closure := NewClosure(function)
+			closure.module = frame.closure.module
+			vm.pushStack(ObjVal{Object: closure})
+			for i := range closure.upvalues {
+				isLocal := vm.readByte()
+				index := int(vm.readByte())
+				if isLocal == 1 {
+					closure.upvalues[i] = vm.captureUpvalue(frame.slots + index)
+				} else {
+					closure.upvalues[i] = frame.closure.upvalues[index]
+				}
+			}
+		case OP_CLASS:
+			vm.pushStack(ObjVal{Object: NewClass(vm.readString())})
+		case OP_GET_PROPERTY:
+			if IsModule(vm.peek(0)) {
+				if !vm.moduleProperty(vm.readString()) {
+					return INTERPRET_RUNTIME_ERROR
+				}
+				break
+			}
+			if IsError(vm.peek(0)) {
+				if !vm.errorProperty(vm.readString()) {
+					return INTERPRET_RUNTIME_ERROR
+				}
+				break
+			}
+			if !IsInstance(vm.peek(0)) {
+				vm.runtimeError("Only instances have properties.")
+				return INTERPRET_RUNTIME_ERROR
+			}
+			instance := AsInstance(vm.peek(0))
+			name := vm.readString()
+			if value, ok := instance.fields.TableGet(name); ok {
+				vm.popStack()
+				vm.pushStack(value)
+				break
+			}
+			if !vm.bindMethod(instance.klass, name) {
+				return INTERPRET_RUNTIME_ERROR
+			}
+		case OP_SET_PROPERTY:
+			if !IsInstance(vm.peek(1)) {
+				vm.runtimeError("Only instances have fields.")
+				return INTERPRET_RUNTIME_ERROR
+			}
+			instance := AsInstance(vm.peek(1))
+			instance.fields.TableSet(vm.readString(), vm.peek(0))
+			value := vm.popStack()
+			vm.popStack()
+			vm.pushStack(value)
+		case OP_METHOD:
+			vm.defineMethod(vm.readString())
+		case OP_INHERIT:
+			superclass := vm.peek(1)
+			if !IsClass(superclass) {
+				vm.runtimeError("Superclass must be a class.")
+				return INTERPRET_RUNTIME_ERROR
+			}
+			subclass := AsClass(vm.peek(0))
+			TableAddAll(AsClass(superclass).methods, subclass.methods)
+			vm.popStack()
+		case OP_GET_SUPER:
+			name := vm.readString()
+			superclass := AsClass(vm.popStack())
+			if !vm.bindMethod(superclass, name) {
+				return INTERPRET_RUNTIME_ERROR
+			}
+		case OP_BUILD_LIST:
+			itemCount := int(vm.readByte())
+			items := make([]Value, itemCount)
+			copy(items, vm.stack[len(vm.stack)-itemCount:])
+			vm.stack = vm.stack[:len(vm.stack)-itemCount]
+			vm.pushStack(ObjVal{Object: NewList(items)})
+		case OP_BUILD_MAP:
+			if !vm.buildMap(int(vm.readByte())) {
+				return INTERPRET_RUNTIME_ERROR
+			}
+		case OP_IMPORT:
+			if !vm.importModule(vm.readString()) {
+				return INTERPRET_RUNTIME_ERROR
+			}
+			frame = vm.getCurrentFrame()
+		case OP_IMPORT_FROM:
+			module := AsModule(vm.peek(0))
+			name := vm.readString()
+			value, ok := module.export(name)
+			if !ok {
+				vm.runtimeError("Module '%s' does not export '%s'.", module.name, name.Characters)
+				return INTERPRET_RUNTIME_ERROR
+			}
+			vm.pushStack(value)
+		case OP_EXPORT:
+			frame.closure.module.exports[vm.readString()] = true
+		case OP_TRY:
+			offset := vm.readShort()
+			vm.handlers = append(vm.handlers, ExceptionHandler{
+				frameCount: vm.frameCount,
+				stackLen:   len(vm.stack),
+				ip:         frame.ip + offset,
+			})
+		case OP_END_TRY:
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+		case OP_THROW:
+			vm.throwValue(vm.popStack())
+			return INTERPRET_RUNTIME_ERROR
+		case OP_SAVE_THROWN:
+			thrown := &ObjThrown{value: vm.popStack(), err: vm.lastError}
+			vm.pushStack(ObjVal{Object: thrown})
+		case OP_POPN:
+			vm.stack = vm.stack[:len(vm.stack)-int(vm.readByte())]
+		case OP_TO_STRING:
+			if !IsString(vm.peek(0)) {
+				vm.pushStack(ObjVal{Object: CreateStringObj(vm.popStack().String())})
+			}
+		case OP_INDEX_GET:
+			if !vm.indexGet() {
+				return INTERPRET_RUNTIME_ERROR
+			}
+		case OP_INDEX_SET:
+			if !vm.indexSet() {
+				return INTERPRET_RUNTIME_ERROR
+			}
+		case OP_GET_UPVALUE:
+			slot := vm.readByte()
+			vm.pushStack(vm.readUpvalue(frame.closure.upvalues[slot]))
+		case OP_SET_UPVALUE:
+			slot := vm.readByte()
+			vm.writeUpvalue(frame.closure.upvalues[slot], vm.peek(0))
+		case OP_CLOSE_UPVALUE:
+			vm.closeUpvalues(len(vm.stack) - 1)
+			vm.popStack()
+		}
+	}
+}
+
+func (vm *VM) traceInstruction(frame *CallFrame) {
+	w := vm.debug.writer()
+	fmt.Fprint(w, "          ")
+	for _, slot := range vm.stack {
+		fmt.Fprintf(w, "[ %s ]", slot.String())
+	}
+	fmt.Fprintln(w)
+	disassembleInstruction(w, &frame.closure.function.chunk, frame.ip)
+}
+
+func (vm *VM) callValue(callee Value, argCount int) bool {
+	if isObj(callee) {
+		switch callee.AsObj().Type() {
+		case OBJ_CLOSURE:
+			return vm.call(AsClosure(callee), argCount)
+		case OBJ_BOUND_METHOD:
+			bound := AsBoundMethod(callee)
+			vm.stack[len(vm.stack)-argCount-1] = bound.receiver
+			return vm.call(bound.method, argCount)
+		case OBJ_NATIVE:
+			return vm.callNative(AsNative(callee), argCount)
+		case OBJ_CLASS:
+			klass := AsClass(callee)
+			vm.stack[len(vm.stack)-argCount-1] = ObjVal{Object: NewInstance(klass)}
+			if initializer, ok := klass.methods.TableGet(vm.initString); ok {
+				return vm.call(AsClosure(initializer), argCount)
+			} else if argCount != 0 {
+				vm.runtimeError("Expected 0 arguments but got %d.", argCount)
+				return false
+			}
+			return true
+		}
+	}
+	vm.runtimeError("Can only call functions and classes.")
+	return false
+}
+
+// callNamed calls callee with argCount arguments, the last len(names) of
+// which were passed by name. Only Lox functions take named arguments.
+func (vm *VM) callNamed(callee Value, argCount int, names []ObjString) bool {
+	var closure *ObjClosure
+	switch {
+	case IsClosure(callee):
+		closure = AsClosure(callee)
+	case IsBoundMethod(callee):
+		bound := AsBoundMethod(callee)
+		vm.stack[len(vm.stack)-argCount-1] = bound.receiver
+		closure = bound.method
+	case IsClass(callee):
+		klass := AsClass(callee)
+		initializer, ok := klass.methods.TableGet(vm.initString)
+		if !ok {
+			vm.runtimeError("Expected 0 arguments but got %d.", argCount)
+			return false
+		}
+		vm.stack[len(vm.stack)-argCount-1] = ObjVal{Object: NewInstance(klass)}
+		closure = AsClosure(initializer)
+	case IsNative(callee):
+		vm.runtimeError("%s() doesn't take named arguments.", AsNative(callee).name)
+		return false
+	default:
+		vm.runtimeError("Can only call functions and classes.")
+		return false
+	}
+	if !vm.bindArguments(closure.function, argCount, names) {
+		return false
+	}
+	return vm.pushFrame(closure)
+}
+
+func (vm *VM) call(closure *ObjClosure, argCount int) bool {
+	function := closure.function
+	if argCount != function.arity || function.minArity != function.arity || function.variadic {
+		if !vm.bindArguments(function, argCount, nil) {
+			return false
+		}
+	}
+	return vm.pushFrame(closure)
+}
+
+// bindArguments rearranges the argCount arguments on top of the stack, the
+// last len(names) of which were passed by name, into one slot per
+// parameter. Optional parameters that weren't passed hold missingArg until
+// the function's prologue evaluates their defaults.
+func (vm *VM) bindArguments(function *ObjFunction, argCount int, names []ObjString) bool {
+	positional := argCount - len(names)
+	args := make([]Value, argCount)
+	copy(args, vm.stack[len(vm.stack)-argCount:])
+	vm.stack = vm.stack[:len(vm.stack)-argCount]
+
+	if msg := arityError(function, positional, len(names) > 0); msg != "" {
+		vm.runtimeError("%s", msg)
+		return false
+	}
+	slots := make([]Value, function.arity)
+	for i := range slots {
+		slots[i] = missingArg{}
+	}
+	copy(slots, args[:min(positional, function.arity)])
+	for i, name := range names {
+		index := slices.Index(function.params, name.Characters)
+		if index == -1 {
+			vm.runtimeError("%s() has no parameter named '%s'.", functionName(function), name.Characters)
+			return false
+		}
+		if _, missing := slots[index].(missingArg); !missing {
+			vm.runtimeError("%s() got more than one value for '%s'.", functionName(function), name.Characters)
+			return false
+		}
+		slots[index] = args[positional+i]
+	}
+	for i := 0; i < function.minArity; i++ {
+		if _, missing := slots[i].(missingArg); missing {
+			vm.runtimeError("%s() is missing argument '%s'.", functionName(function), function.params[i])
+			return false
+		}
+	}
+
+	vm.stack = append(vm.stack, slots...)
+	if function.variadic {
+		var rest []Value
+		if positional > function.arity {
+			rest = append(rest, args[function.arity:positional]...)
+		}
+		vm.pushStack(ObjVal{Object: NewList(rest)})
+	}
+	return true
+}
+
+// arityError checks a call passing positional arguments by position. When
+// some are passed by name, missing parameters are reported later by name.
+func arityError(function *ObjFunction, positional int, hasNamed bool) string {
+	switch {
+	case positional > function.arity && !function.variadic:
+		if function.minArity == function.arity {
+			return fmt.Sprintf("Expected %d arguments but got %d.", function.arity, positional)
+		}
+		return fmt.Sprintf("Expected at most %d arguments but got %d.", function.arity, positional)
+	case positional < function.minArity && !hasNamed:
+		if function.minArity == function.arity && !function.variadic {
+			return fmt.Sprintf("Expected %d arguments but got %d.", function.arity, positional)
+		}
+		return fmt.Sprintf("Expected at least %d arguments but got %d.", function.minArity, positional)
+	}
+	return ""
+}
+
+func (vm *VM) pushFrame(closure *ObjClosure) bool {
+	if vm.frameCount == FRAME_MAX {
+		vm.runtimeError("Stack overflow.")
+		return false
+	}
+
+	slotCount := closure.function.arity
+	if closure.function.variadic {
+		slotCount++
+	}
+	frame := CallFrame{}
+	frame.closure = closure
+	frame.ip = 0
+	frame.slots = len(vm.stack) - slotCount - 1
+	vm.frames = append(vm.frames, frame)
+	vm.frameCount += 1
+	return true
+}
+
+func (vm *VM) callNative(native *ObjNative, argCount int) bool {
+	if native.arity != -1 && argCount != native.arity {
+		vm.runtimeError("Expected %d arguments but got %d.",
+			native.arity, argCount)
+		return false
+	}
+
+	args := make([]Value, argCount)
+	copy(args, vm.stack[len(vm.stack)-argCount:])
+	result, err := native.function(args)
+	if err != nil {
+		vm.runtimeError("%s", err.Error())
+		return false
+	}
+
+	vm.stack = vm.stack[:len(vm.stack)-argCount-1]
+	vm.pushStack(result)
+	return true
+}
+
+func (vm *VM) buildMap(entryCount int) bool {
+	m := NewMap()
+	entries := vm.stack[len(vm.stack)-2*entryCount:]
+	for i := 0; i < len(entries); i += 2 {
+		if err := checkKey(entries[i]); err != nil {
+			vm.runtimeError("%s", err.Error())
+			return false
+		}
+		m.set(entries[i], entries[i+1])
+	}
+	vm.stack = vm.stack[:len(vm.stack)-2*entryCount]
+	vm.pushStack(ObjVal{Object: m})
+	return true
+}
+
+func (vm *VM) indexGet() bool {
+	index := vm.popStack()
+	target := vm.popStack()
+	switch {
+	case IsList(target):
+		list := AsList(target)
+		i, err := listIndex(index, len(list.items))
+		if err != nil {
+			vm.runtimeError("%s", err.Error())
+			return false
+		}
+		vm.pushStack(list.items[i])
+	case IsMap(target):
+		if err := checkKey(index); err != nil {
+			vm.runtimeError("%s", err.Error())
+			return false
+		}
+		value, ok := AsMap(target).get(index)
+		if !ok {
+			vm.runtimeError("Undefined key %s.", reprString(index))
+			return false
+		}
+		vm.pushStack(value)
+	case IsString(target):
+		chars := []rune(AsLiteralString(target))
+		i, err := listIndex(index, len(chars))
+		if err != nil {
+			vm.runtimeError("%s", err.Error())
+			return false
+		}
+		vm.pushStack(ObjVal{Object: CreateStringObj(string(chars[i]))})
+	default:
+		vm.runtimeError("Can only index lists, maps and strings.")
+		return false
+	}
+	return true
+}
+
+func (vm *VM) indexSet() bool {
+	value := vm.popStack()
+	index := vm.popStack()
+	target := vm.popStack()
+	if IsMap(target) {
+		if err := checkKey(index); err != nil {
+			vm.runtimeError("%s", err.Error())
+			return false
+		}
+		AsMap(target).set(index, value)
+		vm.pushStack(value)
+		return true
+	}
+	if !IsList(target) {
+		vm.runtimeError("Can only assign to list and map elements.")
+		return false
+	}
+	list := AsList(target)
+	i, err := listIndex(index, len(list.items))
+	if err != nil {
+		vm.runtimeError("%s", err.Error())
+		return false
+	}
+	list.items[i] = value
+	vm.pushStack(value)
+	return true
+}
+
+func (vm *VM) errorProperty(name ObjString) bool {
+	err := AsError(vm.peek(0))
+	var value Value
+	switch name.Characters {
+	case "message":
+		value = ObjVal{Object: CreateStringObj(err.message)}
+	case "stack":
+		value = ObjVal{Object: CreateStringObj(traceString(err.trace))}
+	default:
+		vm.runtimeError("Undefined property '%s'.", name.Characters)
+		return false
+	}
+	vm.popStack()
+	vm.pushStack(value)
+	return true
+}
+
+func (vm *VM) bindMethod(klass *ObjClass, name ObjString) bool {
+	method, ok := klass.methods.TableGet(name)
+	if !ok {
+		vm.runtimeError("Undefined property '%s'.", name.Characters)
+		return false
+	}
+
+	bound := NewBoundMethod(vm.peek(0), AsClosure(method))
+	vm.popStack()
+	vm.pushStack(ObjVal{Object: bound})
+	return true
+}
+
+func (vm *VM) defineMethod(name ObjString) {
+	method := vm.peek(0)
+	klass := AsClass(vm.peek(1))
+	klass.methods.TableSet(name, method)
+	vm.popStack()
+}
+
+func (vm *VM) captureUpvalue(local int) *ObjUpvalue {
+	var prevUpvalue *ObjUpvalue
+	upvalue := vm.openUpvalues
+	for upvalue != nil && upvalue.location > local {
+		prevUpvalue = upvalue
+		upvalue = upvalue.next
+	}
+
+	if upvalue != nil && upvalue.location == local {
+		return upvalue
+	}
+
+	createdUpvalue := NewUpvalue(local)
+	createdUpvalue.next = upvalue
+	if prevUpvalue == nil {
+		vm.openUpvalues = createdUpvalue
+	} else {
+		prevUpvalue.next = createdUpvalue
+	}
+	return createdUpvalue
+}
+
+// closeUpvalues moves every open upvalue pointing at or above the given stack
+// slot off the stack, so closures keep the value after the slot is discarded.
+func (vm *VM) closeUpvalues(last int) {
+	for vm.openUpvalues != nil && vm.openUpvalues.location >= last {
+		upvalue := vm.openUpvalues
+		upvalue.closed = vm.stack[upvalue.location]
+		upvalue.isClosed = true
+		vm.openUpvalues = upvalue.next
+	}
+}
+
+func (vm *VM) readUpvalue(upvalue *ObjUpvalue) Value {
+	if upvalue.isClosed {
+		return upvalue.closed
+	}
+	return vm.stack[upvalue.location]
+}
+
+func (vm *VM) writeUpvalue(upvalue *ObjUpvalue, val Value) {
+	if upvalue.isClosed {
+		upvalue.closed = val
+		return
+	}
+	vm.stack[upvalue.location] = val
+}
+
+func (vm *VM) readShort() int {
+	frame := vm.getCurrentFrame()
+	frame.ip += 2
+	high := uint16(frame.closure.function.chunk.Code[frame.ip-2])
+	low := uint16(frame.closure.function.chunk.Code[frame.ip-1])
+
+	return int((high << 8) | low)
+}
+
+func (vm *VM) performBinaryOp(operation byte) bool {
+
+	switch operation {
+	case OP_ADD:
+		if IsString(vm.peek(0)) && IsString(vm.peek(1)) {
+			b := AsString(vm.popStack())
+			a := AsString(vm.popStack())
+			vm.pushStack(ObjVal{Object: CreateStringObj(a.Characters + b.Characters)})
+		} else if isNumber(vm.peek(0)) && isNumber(vm.peek(1)) {
+			return vm.performArithmetic(operation)
+		} else {
+			vm.runtimeError("Operands must be two numbers or two strings")
+			return false
+		}
+	case OP_DIVIDE, OP_MULTIPLY, OP_SUBSTRACT, OP_FLOOR_DIVIDE, OP_MODULO, OP_POWER:
+		if !isNumber(vm.peek(0)) || !isNumber(vm.peek(1)) {
+			vm.runtimeError("Operands must be numbers.")
+			return false
+		}
+		return vm.performArithmetic(operation)
+	case OP_BIT_AND, OP_BIT_OR, OP_BIT_XOR, OP_SHIFT_LEFT, OP_SHIFT_RIGHT:
+		if !isInt(vm.peek(0)) || !isInt(vm.peek(1)) {
+			vm.runtimeError("Operands must be integers.")
+			return false
+		}
+		b := vm.popStack().AsInt()
+		a := vm.popStack().AsInt()
+		result, err := bitwise(operation, a, b)
+		if err != nil {
+			vm.runtimeError("%s", err.Error())
+			return false
+		}
+		vm.pushStack(result)
+	case OP_GREATER, OP_LESS:
+		if !isNumber(vm.peek(0)) || !isNumber(vm.peek(1)) {
+			vm.runtimeError("Operands must be numbers.")
+			return false
+		}
+		b := vm.popStack()
+		a := vm.popStack()
+		order, ok := compareNumbers(a, b)
+		if operation == OP_GREATER {
+			vm.pushStack(BoolVal(ok && order > 0))
+		} else {
+			vm.pushStack(BoolVal(ok && order < 0))
+		}
+	}
+	return true
+}
+
+func (vm *VM) performArithmetic(operation byte) bool {
+	b := vm.popStack()
+	a := vm.popStack()
+	result, err := arithmetic(operation, a, b)
+	if err != nil {
+		vm.runtimeError("%s", err.Error())
+		return false
+	}
+	vm.pushStack(result)
+	return true
+}
+
+func (vm *VM) readByte() byte {
+	frame := vm.getCurrentFrame()
+	inst := frame.closure.function.chunk.Code[frame.ip]
+	frame.ip += 1
+	return inst
+}
+
+func (vm *VM) readConstant() Value {
+	frame := vm.getCurrentFrame()
+	return frame.closure.function.chunk.Constants.values[vm.readByte()]
+}
+
+func (vm *VM) readString() ObjString {
+	return AsString(vm.readConstant())
+}
+
+func (vm *VM) resetStack() {
+	vm.stack = []Value{}
+	vm.frameCount = 0
+	vm.frames = []CallFrame{}
+	vm.openUpvalues = nil
+	vm.handlers = nil
+}
+
+func (vm *VM) pushStack(val Value) {
+	vm.stack = append(vm.stack, val)
+}
+
+func (vm *VM) popStack() Value {
+	stackLen := len(vm.stack)
+	val := vm.stack[stackLen-1]
+	vm.stack = vm.stack[:stackLen-1]
+
+	return val
+}
+
+func (vm *VM) peek(distance int) Value {
+	return vm.stack[len(vm.stack)-1-distance]
+}
+
+func isFalsey(v Value) bool {
+	return isNil(v) || (isBool(v) && !v.AsBoolean())
+}
+
+// runtimeError throws an error object carrying the current call stack. The
+// caller must return INTERPRET_RUNTIME_ERROR so run can unwind to a handler.
+func (vm *VM) runtimeError(format string, a ...any) {
+	message := fmt.Sprintf(format, a...)
+	trace := vm.stackTrace()
+	vm.thrown = ObjVal{Object: NewError(message, trace)}
+	vm.lastError = &RuntimeError{Message: message, Trace: trace}
+}
+
+// throwValue starts unwinding with val. If nothing catches it, lastError
+// describes it. A value saved by a finally clause is rethrown as it was
+// first thrown.
+func (vm *VM) throwValue(val Value) {
+	if IsObjtype(val, OBJ_THROWN) {
+		thrown := val.AsObj().(*ObjThrown)
+		vm.thrown = thrown.value
+		vm.lastError = thrown.err
+		return
+	}
+	vm.thrown = val
+	if IsError(val) {
+		err := AsError(val)
+		vm.lastError = &RuntimeError{Message: err.message, Trace: err.trace}
+		return
+	}
+	vm.lastError = &RuntimeError{
+		Message: "Uncaught exception: " + reprString(val),
+		Trace:   vm.stackTrace(),
+	}
+}
+
+func (vm *VM) stackTrace() []StackFrame {
+	var trace []StackFrame
+	for i := vm.frameCount - 1; i >= 0; i-- {
+		frame := vm.frames[i]
+		function := frame.closure.function
+		stackFrame := StackFrame{
+			Function: "script",
+			Span:     function.chunk.spans[frame.ip-1],
+		}
+		if function.source != nil {
+			stackFrame.Source = function.source.Name
+			stackFrame.Text = lineText(function.source.Text, stackFrame.Offset)
+		}
+		if function.name != nil {
+			stackFrame.Function = function.name.Characters
+		}
+		if module := frame.closure.module; module != vm.main && function == module.function {
+			stackFrame.InModule = true
+		}
+		trace = append(trace, stackFrame)
+	}
+	return trace
+}
+
+func (vm *VM) getCurrentFrame() *CallFrame {
+	return &vm.frames[vm.frameCount-1]
+}